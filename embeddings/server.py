@@ -1,5 +1,6 @@
 import os
-from fastapi import FastAPI
+import time
+from fastapi import FastAPI, Request
 from pydantic import BaseModel
 from sentence_transformers import SentenceTransformer
 import numpy as np
@@ -23,9 +24,18 @@ rerank_model.eval()
 
 app = FastAPI()
 
+@app.middleware("http")
+async def log_request(request: Request, call_next):
+    start = time.perf_counter()
+    response = await call_next(request)
+    duration_ms = (time.perf_counter() - start) * 1000
+    request_id = request.headers.get("x-request-id", "-")
+    print(f"{request.method} {request.url.path} {response.status_code} {duration_ms:.1f}ms request_id={request_id}", flush=True)
+    return response
+
 @app.get("/health")
 def health():
-    return {"status": "ok"}
+    return {"status": "ok", "embedding_model": EMBEDDING_MODEL_NAME, "rerank_model": RERANK_MODEL_NAME}
 
 class EmbedRequest(BaseModel):
     texts: list[str]
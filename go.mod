@@ -7,9 +7,7 @@ toolchain go1.24.7
 require (
 	github.com/yuin/goldmark v1.7.13
 	go.abhg.dev/goldmark/frontmatter v0.3.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
-require (
-	github.com/BurntSushi/toml v1.5.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
-)
+require github.com/BurntSushi/toml v1.5.0
@@ -0,0 +1,49 @@
+// Package analytics records what users search for, so that searches which find nothing can drive new pages.
+// Only the anonymized query and what the search found are recorded, nothing about the client.
+package analytics
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const maxQueryLength = 200
+
+var (
+	emailPattern  = regexp.MustCompile(`\S+@\S+`)
+	urlPattern    = regexp.MustCompile(`(?i)\b[a-z][a-z0-9+.-]*://\S+`)
+	numberPattern = regexp.MustCompile(`\d[\d\s-]{4,}\d`)
+)
+
+type Event struct {
+	Time     time.Time `json:"time"`
+	Language string    `json:"language"`
+	Query    string    `json:"query"`
+	Results  int       `json:"results"`
+	TopScore float32   `json:"topScore"`
+	// Mode is lexical or semantic
+	Mode      string `json:"mode"`
+	Corrected bool   `json:"corrected,omitempty"`
+}
+
+// Sink stores the events, implementations must be safe for concurrent use.
+type Sink interface {
+	Record(ctx context.Context, event Event) error
+	Close() error
+}
+
+// Anonymize normalizes the query and removes what could identify a person, like e-mail addresses,
+// URLs or long numbers (phone numbers, IMEIs).
+func Anonymize(query string) string {
+	query = emailPattern.ReplaceAllString(query, "<email>")
+	query = urlPattern.ReplaceAllString(query, "<url>")
+	query = numberPattern.ReplaceAllString(query, "<number>")
+	query = strings.Join(strings.Fields(strings.ToLower(query)), " ")
+
+	if runes := []rune(query); len(runes) > maxQueryLength {
+		query = string(runes[:maxQueryLength])
+	}
+	return query
+}
@@ -0,0 +1,41 @@
+package analytics
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestAnonymize(t *testing.T) {
+	for _, current := range []struct {
+		query, expected string
+	}{
+		{"How to Install Apps", "how to install apps"},
+		{"  too   MANY\tspaces\n", "too many spaces"},
+		{"mail John.Doe+sfos@Example.com about it", "mail <email> about it"},
+		{"see HTTPS://forum.sailfishos.org/t/123?user=me now", "see <url> now"},
+		{"ftp://files.example.org/a", "<url>"},
+		{"imei 35-209900-176148-1 blocked", "imei <number> blocked"},
+		{"imei 352099001761481", "imei <number>"},
+		{"call +49 170 1234567", "call +<number>"},
+		// versions, models and short numbers are what people search for
+		{"Sailfish 4.5 on Xperia 10 III", "sailfish 4.5 on xperia 10 iii"},
+		{"error 1234", "error 1234"},
+	} {
+		if actual := Anonymize(current.query); actual != current.expected {
+			t.Errorf("Anonymize(%q) = %q, expected %q", current.query, actual, current.expected)
+		}
+	}
+}
+
+func TestAnonymizeTruncatesOnRuneBoundaries(t *testing.T) {
+	for _, letter := range []string{"a", "ä", "日", "🐟"} {
+		actual := Anonymize(strings.Repeat(letter, maxQueryLength+50))
+		if !utf8.ValidString(actual) {
+			t.Errorf("%s: truncated query is not valid UTF-8", letter)
+		}
+		if length := utf8.RuneCountInString(actual); length != maxQueryLength {
+			t.Errorf("%s: truncated to %d letters, expected %d", letter, length, maxQueryLength)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var ErrSinkClosed = errors.New("analytics sink closed")
+
+// droppedLogInterval is how often the number of dropped events is logged
+const droppedLogInterval = time.Minute
+
+// AsyncSink hands the events to the underlying sink from a single goroutine, searches only wait for
+// a free slot in the buffer. Events which don't fit are counted in analytics_dropped_events_total and
+// logged once per interval.
+type AsyncSink struct {
+	sink    Sink
+	events  chan Event
+	done    chan struct{}
+	dropped atomic.Int64
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+func NewAsyncSink(sink Sink, buffer int) *AsyncSink {
+	async := &AsyncSink{
+		sink:   sink,
+		events: make(chan Event, buffer),
+		done:   make(chan struct{}),
+	}
+	go async.run()
+	return async
+}
+
+func (receiver *AsyncSink) Record(_ context.Context, event Event) error {
+	receiver.mu.RLock()
+	defer receiver.mu.RUnlock()
+	if receiver.closed {
+		return ErrSinkClosed
+	}
+
+	select {
+	case receiver.events <- event:
+	default:
+		receiver.dropped.Add(1)
+		droppedEvents.Inc()
+	}
+	return nil
+}
+
+// Close records the buffered events and closes the underlying sink.
+func (receiver *AsyncSink) Close() error {
+	receiver.mu.Lock()
+	if receiver.closed {
+		receiver.mu.Unlock()
+		return ErrSinkClosed
+	}
+	receiver.closed = true
+	close(receiver.events)
+	receiver.mu.Unlock()
+
+	<-receiver.done
+	return receiver.sink.Close()
+}
+
+func (receiver *AsyncSink) run() {
+	defer close(receiver.done)
+	ticker := time.NewTicker(droppedLogInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event, ok := <-receiver.events:
+			if !ok {
+				receiver.logDropped()
+				return
+			}
+			if err := receiver.sink.Record(context.Background(), event); err != nil {
+				slog.Warn("failed recording search analytics", "error", err)
+			}
+		case <-ticker.C:
+			receiver.logDropped()
+		}
+	}
+}
+
+func (receiver *AsyncSink) logDropped() {
+	if dropped := receiver.dropped.Swap(0); dropped > 0 {
+		slog.Warn("dropped search analytics events, the sink can't keep up", "dropped", dropped, "buffer", cap(receiver.events))
+	}
+}
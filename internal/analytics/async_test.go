@@ -0,0 +1,59 @@
+package analytics
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+// blockingSink keeps the events until it's released.
+type blockingSink struct {
+	release chan struct{}
+
+	mu     sync.Mutex
+	events []Event
+	closed bool
+}
+
+func (receiver *blockingSink) Record(_ context.Context, event Event) error {
+	<-receiver.release
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	receiver.events = append(receiver.events, event)
+	return nil
+}
+
+func (receiver *blockingSink) Close() error {
+	receiver.closed = true
+	return nil
+}
+
+func TestAsyncSinkCountsDroppedEvents(t *testing.T) {
+	sink := &blockingSink{release: make(chan struct{})}
+	async := NewAsyncSink(sink, 2)
+
+	for _, query := range []string{"a", "b", "c", "d", "e", "f"} {
+		if err := async.Record(context.Background(), Event{Query: query}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// two events are buffered and one may already be being recorded
+	dropped := int(async.dropped.Load())
+	if dropped < 3 || dropped > 4 {
+		t.Errorf("dropped %d events, expected 3 or 4", dropped)
+	}
+
+	close(sink.release)
+	if err := async.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if recorded := len(sink.events); recorded != 6-dropped {
+		t.Errorf("recorded %d events, expected the %d which weren't dropped", recorded, 6-dropped)
+	}
+	if !sink.closed {
+		t.Error("the underlying sink wasn't closed")
+	}
+	if err := async.Record(context.Background(), Event{}); err != ErrSinkClosed {
+		t.Errorf("recording after closing returned %v, expected %v", err, ErrSinkClosed)
+	}
+}
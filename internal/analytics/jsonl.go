@@ -0,0 +1,62 @@
+package analytics
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// JSONLSink appends every event as a JSON line to a file.
+type JSONLSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func NewJSONLSink(path string) (*JSONLSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open analytics file %s: %w", path, err)
+	}
+	return &JSONLSink{file: file}, nil
+}
+
+func (receiver *JSONLSink) Record(_ context.Context, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode analytics event: %w", err)
+	}
+
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	if _, err := receiver.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write analytics event: %w", err)
+	}
+	return nil
+}
+
+func (receiver *JSONLSink) Close() error {
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	return receiver.file.Close()
+}
+
+// ReadJSONL reads the events written by JSONLSink, lines which can't be decoded are skipped.
+func ReadJSONL(reader io.Reader) ([]Event, error) {
+	var events []Event
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue
+		}
+		events = append(events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read analytics events: %w", err)
+	}
+	return events, nil
+}
@@ -0,0 +1,8 @@
+package analytics
+
+import "SfosBeginnerGuide/internal/metrics"
+
+var droppedEvents = metrics.Default.NewCounter(
+	"analytics_dropped_events_total",
+	"Number of search analytics events dropped because the buffer of the sink was full.",
+)
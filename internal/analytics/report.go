@@ -0,0 +1,138 @@
+package analytics
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+)
+
+type ReportOptions struct {
+	// Limit is the number of queries listed in every section
+	Limit int
+	// LowScores are the top scores below which a search of the mode (semantic or lexical) is considered to
+	// have found nothing useful. The modes score differently, the cosine similarity of semantic searches can't
+	// be compared to the share of words found by lexical ones. Searches of other modes are never low scoring.
+	LowScores map[string]float32
+}
+
+type Report struct {
+	Languages []*LanguageReport
+}
+
+type LanguageReport struct {
+	Language    string
+	Searches    int
+	TopQueries  []*QueryStats
+	ZeroResults []*QueryStats
+	LowScore    []*QueryStats
+}
+
+type QueryStats struct {
+	Query string
+	// Mode is only set for the low scoring queries, which are listed per mode
+	Mode     string
+	Count    int
+	TopScore float32
+}
+
+func NewReport(events []Event, options ReportOptions) *Report {
+	byLanguage := make(map[string][]Event)
+	for _, event := range events {
+		byLanguage[event.Language] = append(byLanguage[event.Language], event)
+	}
+
+	report := &Report{}
+	for _, language := range slices.Sorted(maps.Keys(byLanguage)) {
+		languageEvents := byLanguage[language]
+		report.Languages = append(report.Languages, &LanguageReport{
+			Language:   language,
+			Searches:   len(languageEvents),
+			TopQueries: queryStats(languageEvents, options.Limit, false, func(Event) bool { return true }),
+			ZeroResults: queryStats(languageEvents, options.Limit, false, func(event Event) bool {
+				return event.Results == 0
+			}),
+			LowScore: queryStats(languageEvents, options.Limit, true, func(event Event) bool {
+				lowScore, ok := options.LowScores[event.Mode]
+				return ok && event.Results > 0 && event.TopScore < lowScore
+			}),
+		})
+	}
+	return report
+}
+
+// queryStats groups the matching events by query, and by mode too when byMode is set, the most frequent
+// queries first.
+func queryStats(events []Event, limit int, byMode bool, matches func(Event) bool) []*QueryStats {
+	type groupKey struct{ query, mode string }
+	byQuery := make(map[groupKey]*QueryStats)
+	for _, event := range events {
+		if !matches(event) {
+			continue
+		}
+		key := groupKey{query: event.Query}
+		if byMode {
+			key.mode = event.Mode
+		}
+		stats, ok := byQuery[key]
+		if !ok {
+			stats = &QueryStats{Query: key.query, Mode: key.mode}
+			byQuery[key] = stats
+		}
+		stats.Count++
+		stats.TopScore = max(stats.TopScore, event.TopScore)
+	}
+
+	result := slices.SortedFunc(maps.Values(byQuery), func(a, b *QueryStats) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Query, b.Query), cmp.Compare(a.Mode, b.Mode))
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+func (receiver *Report) Print(writer io.Writer) error {
+	if len(receiver.Languages) == 0 {
+		_, err := fmt.Fprintln(writer, "No searches recorded")
+		return err
+	}
+
+	for _, language := range receiver.Languages {
+		if _, err := fmt.Fprintf(writer, "== %s (%d searches)\n", language.Language, language.Searches); err != nil {
+			return err
+		}
+		sections := []struct {
+			title   string
+			queries []*QueryStats
+		}{
+			{"Top queries", language.TopQueries},
+			{"Queries without results", language.ZeroResults},
+			{"Queries with low scores", language.LowScore},
+		}
+		for _, section := range sections {
+			if _, err := fmt.Fprintf(writer, "\n%s:\n", section.title); err != nil {
+				return err
+			}
+			if len(section.queries) == 0 {
+				if _, err := fmt.Fprintln(writer, "  none"); err != nil {
+					return err
+				}
+			}
+			for _, stats := range section.queries {
+				query := stats.Query
+				if stats.Mode != "" {
+					query += " (" + stats.Mode + ")"
+				}
+				if _, err := fmt.Fprintf(writer, "  %5d  %.3f  %s\n", stats.Count, stats.TopScore, query); err != nil {
+					return err
+				}
+			}
+		}
+		if _, err := fmt.Fprintln(writer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
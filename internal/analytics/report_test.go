@@ -0,0 +1,80 @@
+package analytics
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func formatStats(stats []*QueryStats) string {
+	parts := make([]string, 0, len(stats))
+	for _, current := range stats {
+		part := fmt.Sprintf("%s:%d:%.2f", current.Query, current.Count, current.TopScore)
+		if current.Mode != "" {
+			part += ":" + current.Mode
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestNewReport(t *testing.T) {
+	events := []Event{
+		{Language: "en", Query: "store", Results: 5, TopScore: 0.8, Mode: "semantic"},
+		{Language: "en", Query: "store", Results: 5, TopScore: 0.9, Mode: "semantic"},
+		{Language: "en", Query: "store", Results: 3, TopScore: 1, Mode: "lexical"},
+		{Language: "en", Query: "vpn", Results: 0, Mode: "semantic"},
+		{Language: "en", Query: "vpn", Results: 0, Mode: "lexical"},
+		{Language: "en", Query: "banking", Results: 2, TopScore: 0.4, Mode: "semantic"},
+		{Language: "en", Query: "banking", Results: 2, TopScore: 0.5, Mode: "lexical"},
+		// a lexical score of 0.6 is not low for a semantic search and the other way round
+		{Language: "en", Query: "gps fix", Results: 1, TopScore: 0.6, Mode: "semantic"},
+		{Language: "en", Query: "gps fix", Results: 1, TopScore: 0.6, Mode: "lexical"},
+		{Language: "en", Query: "unknown mode", Results: 1, TopScore: 0.1, Mode: "other"},
+		{Language: "de", Query: "store", Results: 1, TopScore: 0.7, Mode: "semantic"},
+	}
+
+	report := NewReport(events, ReportOptions{Limit: 3, LowScores: map[string]float32{"semantic": 0.5, "lexical": 1}})
+	if len(report.Languages) != 2 || report.Languages[0].Language != "de" || report.Languages[1].Language != "en" {
+		t.Fatalf("languages %+v, expected de and en", report.Languages)
+	}
+
+	en := report.Languages[1]
+	if en.Searches != 10 {
+		t.Errorf("%d searches, expected 10", en.Searches)
+	}
+	for _, current := range []struct {
+		section  string
+		stats    []*QueryStats
+		expected string
+	}{
+		{"top queries", en.TopQueries, "store:3:1.00 banking:2:0.50 gps fix:2:0.60"},
+		{"zero results", en.ZeroResults, "vpn:2:0.00"},
+		{"low scores", en.LowScore, "banking:1:0.50:lexical banking:1:0.40:semantic gps fix:1:0.60:lexical"},
+	} {
+		if actual := formatStats(current.stats); actual != current.expected {
+			t.Errorf("%s: %s, expected %s", current.section, actual, current.expected)
+		}
+	}
+
+	var output bytes.Buffer
+	if err := report.Print(&output); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"== en (10 searches)", "Queries with low scores:\n      1  0.500  banking (lexical)\n"} {
+		if !strings.Contains(output.String(), expected) {
+			t.Errorf("printed report doesn't contain %q:\n%s", expected, output.String())
+		}
+	}
+}
+
+func TestNewReportWithoutEvents(t *testing.T) {
+	var output bytes.Buffer
+	if err := NewReport(nil, ReportOptions{}).Print(&output); err != nil {
+		t.Fatal(err)
+	}
+	if output.String() != "No searches recorded\n" {
+		t.Errorf("printed %q", output.String())
+	}
+}
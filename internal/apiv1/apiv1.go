@@ -0,0 +1,270 @@
+// Package apiv1 holds the response bodies of the /api/v1 endpoints. The types are deliberately separate from
+// the internal models, so that the models can change without breaking the released apps. Fields may be added
+// to these types, but never renamed or removed, and lists are always present (empty rather than null).
+package apiv1
+
+import (
+	"SfosBeginnerGuide/internal/content"
+	"SfosBeginnerGuide/internal/markdown"
+	"SfosBeginnerGuide/internal/search"
+)
+
+type Page struct {
+	Meta          PageMeta  `json:"meta"`
+	NotApplicable bool      `json:"notApplicable"`
+	Overlay       string    `json:"overlay,omitempty"`
+	Content       string    `json:"content"`
+	Blocks        []Block   `json:"blocks,omitempty"`
+	Sections      []Section `json:"sections"`
+	Links         []Link    `json:"links"`
+}
+
+type PageMeta struct {
+	Title              string   `json:"title"`
+	Description        string   `json:"description,omitempty"`
+	Links              []string `json:"links"`
+	Actions            []string `json:"actions"`
+	UnsupportedActions []string `json:"unsupportedActions"`
+	Condition          string   `json:"condition,omitempty"`
+	Devices            []string `json:"devices"`
+	Tags               []string `json:"tags"`
+	Keywords           []string `json:"keywords"`
+	Aliases            []string `json:"aliases"`
+	Author             string   `json:"author,omitempty"`
+	LastReviewed       string   `json:"lastReviewed,omitempty"`
+	Difficulty         string   `json:"difficulty,omitempty"`
+}
+
+type Section struct {
+	Title   string  `json:"title"`
+	Content string  `json:"content"`
+	Blocks  []Block `json:"blocks,omitempty"`
+}
+
+type Link struct {
+	Link  string `json:"link"`
+	Title string `json:"title"`
+}
+
+type Block struct {
+	Type      string     `json:"type"`
+	Level     int        `json:"level,omitempty"`
+	Ordered   bool       `json:"ordered,omitempty"`
+	Start     int        `json:"start,omitempty"`
+	Checked   *bool      `json:"checked,omitempty"`
+	Language  string     `json:"language,omitempty"`
+	Kind      string     `json:"kind,omitempty"`
+	Condition string     `json:"condition,omitempty"`
+	Text      string     `json:"text,omitempty"`
+	Inlines   []Inline   `json:"inlines,omitempty"`
+	Children  []Block    `json:"children,omitempty"`
+	Rows      []TableRow `json:"rows,omitempty"`
+}
+
+type TableRow struct {
+	Header bool        `json:"header,omitempty"`
+	Cells  []TableCell `json:"cells"`
+}
+
+type TableCell struct {
+	Align   string   `json:"align,omitempty"`
+	Inlines []Inline `json:"inlines,omitempty"`
+}
+
+type Inline struct {
+	Type     string   `json:"type"`
+	Text     string   `json:"text,omitempty"`
+	Href     string   `json:"href,omitempty"`
+	Title    string   `json:"title,omitempty"`
+	Action   string   `json:"action,omitempty"`
+	Children []Inline `json:"children,omitempty"`
+}
+
+// Redirect is the body of the 308 responses for pages which have moved.
+type Redirect struct {
+	Path     string `json:"path"`
+	Location string `json:"location"`
+}
+
+type SearchResponse struct {
+	Results    []SearchResult `json:"results"`
+	Facets     SearchFacets   `json:"facets"`
+	Total      int            `json:"total"`
+	Next       string         `json:"next,omitempty"`
+	Suggestion string         `json:"suggestion,omitempty"`
+	Corrected  bool           `json:"corrected,omitempty"`
+}
+
+// SearchEvent is an event of a streamed search, the initial results are followed by the final ones.
+type SearchEvent struct {
+	Stage string `json:"stage"`
+	SearchResponse
+}
+
+type SearchFacets struct {
+	Directories []FacetValue `json:"directories"`
+}
+
+type FacetValue struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+type SearchResult struct {
+	Source string  `json:"source"`
+	Score  float32 `json:"score"`
+}
+
+type LanguagesResponse struct {
+	Languages []string `json:"languages"`
+}
+
+type DevicesResponse struct {
+	Devices []Device `json:"devices"`
+}
+
+type Device struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Architecture string `json:"architecture,omitempty"`
+}
+
+// Presenter converts the internal models to the v1 response bodies.
+type Presenter struct{}
+
+func (Presenter) Page(item *content.Item) any {
+	page := Page{
+		NotApplicable: item.NotApplicable,
+		Overlay:       item.Overlay,
+		Content:       item.Content,
+		Blocks:        newBlocks(item.Blocks),
+		Sections:      make([]Section, 0, len(item.Sections)),
+		Links:         make([]Link, 0, len(item.Links)),
+	}
+	if item.Meta != nil {
+		page.Meta = PageMeta{
+			Title:              item.Meta.Title,
+			Description:        item.Meta.Description,
+			Links:              nonNil(item.Meta.Links),
+			Actions:            nonNil(item.Meta.Actions),
+			UnsupportedActions: nonNil(item.Meta.UnsupportedActions),
+			Condition:          item.Meta.Condition,
+			Devices:            nonNil(item.Meta.Devices),
+			Tags:               nonNil(item.Meta.Tags),
+			Keywords:           nonNil(item.Meta.Keywords),
+			Aliases:            nonNil(item.Meta.Aliases),
+			Author:             item.Meta.Author,
+			LastReviewed:       item.Meta.LastReviewed,
+			Difficulty:         item.Meta.Difficulty,
+		}
+	}
+	for _, section := range item.Sections {
+		page.Sections = append(page.Sections, Section{
+			Title:   section.Title,
+			Content: section.Content,
+			Blocks:  newBlocks(section.Blocks),
+		})
+	}
+	for _, link := range item.Links {
+		page.Links = append(page.Links, Link{Link: link.Link, Title: link.Title})
+	}
+	return page
+}
+
+func (Presenter) Moved(path string, location string) any {
+	return Redirect{Path: path, Location: location}
+}
+
+func (Presenter) SearchResults(searchResponse *search.Response) any {
+	return newSearchResponse(searchResponse)
+}
+
+func (Presenter) SearchEvent(stage search.Stage, searchResponse *search.Response) any {
+	return SearchEvent{Stage: string(stage), SearchResponse: newSearchResponse(searchResponse)}
+}
+
+func newSearchResponse(searchResponse *search.Response) SearchResponse {
+	response := SearchResponse{
+		Results:    make([]SearchResult, 0, len(searchResponse.Results)),
+		Facets:     SearchFacets{Directories: make([]FacetValue, 0, len(searchResponse.Facets.Directories))},
+		Total:      searchResponse.Total,
+		Next:       searchResponse.Next,
+		Suggestion: searchResponse.Suggestion,
+		Corrected:  searchResponse.Corrected,
+	}
+	for _, result := range searchResponse.Results {
+		response.Results = append(response.Results, SearchResult{Source: result.Source, Score: result.Score})
+	}
+	for _, facet := range searchResponse.Facets.Directories {
+		response.Facets.Directories = append(response.Facets.Directories, FacetValue{Value: facet.Value, Count: facet.Count})
+	}
+	return response
+}
+
+func (Presenter) Languages(languages []string) any {
+	return LanguagesResponse{Languages: nonNil(languages)}
+}
+
+func (Presenter) Devices(devices []*content.Device) any {
+	response := DevicesResponse{Devices: make([]Device, 0, len(devices))}
+	for _, device := range devices {
+		response.Devices = append(response.Devices, Device{ID: device.ID, Name: device.Name, Architecture: device.Architecture})
+	}
+	return response
+}
+
+func newBlocks(blocks []*markdown.Block) []Block {
+	if blocks == nil {
+		return nil
+	}
+	result := make([]Block, 0, len(blocks))
+	for _, block := range blocks {
+		converted := Block{
+			Type:      block.Type,
+			Level:     block.Level,
+			Ordered:   block.Ordered,
+			Start:     block.Start,
+			Checked:   block.Checked,
+			Language:  block.Language,
+			Kind:      block.Kind,
+			Condition: block.Condition,
+			Text:      block.Text,
+			Inlines:   newInlines(block.Inlines),
+			Children:  newBlocks(block.Children),
+		}
+		for _, row := range block.Rows {
+			convertedRow := TableRow{Header: row.Header, Cells: make([]TableCell, 0, len(row.Cells))}
+			for _, cell := range row.Cells {
+				convertedRow.Cells = append(convertedRow.Cells, TableCell{Align: cell.Align, Inlines: newInlines(cell.Inlines)})
+			}
+			converted.Rows = append(converted.Rows, convertedRow)
+		}
+		result = append(result, converted)
+	}
+	return result
+}
+
+func newInlines(inlines []*markdown.Inline) []Inline {
+	if inlines == nil {
+		return nil
+	}
+	result := make([]Inline, 0, len(inlines))
+	for _, inline := range inlines {
+		result = append(result, Inline{
+			Type:     inline.Type,
+			Text:     inline.Text,
+			Href:     inline.Href,
+			Title:    inline.Title,
+			Action:   inline.Action,
+			Children: newInlines(inline.Children),
+		})
+	}
+	return result
+}
+
+func nonNil[T any](values []T) []T {
+	if values == nil {
+		return []T{}
+	}
+	return values
+}
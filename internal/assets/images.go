@@ -0,0 +1,249 @@
+package assets
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"SfosBeginnerGuide/internal/cache"
+	"SfosBeginnerGuide/internal/metrics"
+)
+
+var VariantWidths = []int{160, 320, 480, 640, 960, 1280}
+
+var imageContentTypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+}
+
+type Image struct {
+	Path        string
+	Width       int
+	Height      int
+	ContentType string
+}
+
+type Variant struct {
+	Data        []byte
+	ContentType string
+	Width       int
+	Height      int
+}
+
+type ImageStore struct {
+	root     fs.FS
+	images   map[string]*Image
+	variants cache.Store[*Variant]
+}
+
+func NewImageStore(root fs.FS, dir string, variants cache.Store[*Variant]) (*ImageStore, error) {
+	store := &ImageStore{
+		root:     root,
+		images:   make(map[string]*Image),
+		variants: variants,
+	}
+
+	err := fs.WalkDir(root, dir, func(filePath string, entry fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		contentType, ok := imageContentTypes[strings.ToLower(path.Ext(filePath))]
+		if !ok {
+			return nil
+		}
+
+		file, err := root.Open(filePath)
+		if err != nil {
+			return fmt.Errorf("failed to open image %s: %w", filePath, err)
+		}
+		defer file.Close()
+
+		config, _, err := image.DecodeConfig(file)
+		if err != nil {
+			return fmt.Errorf("failed to read image dimensions of %s: %w", filePath, err)
+		}
+
+		store.images[filePath] = &Image{
+			Path:        filePath,
+			Width:       config.Width,
+			Height:      config.Height,
+			ContentType: contentType,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// NewCachedImageStore keeps at most size resized variants in memory.
+func NewCachedImageStore(root fs.FS, dir string, size int, ttl time.Duration) (*ImageStore, error) {
+	return NewImageStore(root, dir, metrics.InstrumentCache("images", cache.NewLRU[*Variant](size, ttl)))
+}
+
+func (receiver *ImageStore) Lookup(imagePath string) (*Image, bool) {
+	img, ok := receiver.images[imagePath]
+	return img, ok
+}
+
+func (receiver *ImageStore) Dimensions(imagePath string) (int, int, bool) {
+	img, ok := receiver.Lookup(imagePath)
+	if !ok {
+		return 0, 0, false
+	}
+	return img.Width, img.Height, true
+}
+
+// Widths returns the variant widths that are smaller than the original image, i.e. the ones
+// that actually save bandwidth when requested.
+func (receiver *ImageStore) Widths(imagePath string) []int {
+	img, ok := receiver.Lookup(imagePath)
+	if !ok {
+		return nil
+	}
+
+	var result []int
+	for _, width := range VariantWidths {
+		if width < img.Width {
+			result = append(result, width)
+		}
+	}
+	return result
+}
+
+// Variant returns the image resized to the closest allowed width that is not smaller than the requested one.
+// A width of zero (or one at least as big as the original) returns the original file.
+func (receiver *ImageStore) Variant(imagePath string, width int) (*Variant, error) {
+	img, ok := receiver.Lookup(imagePath)
+	if !ok {
+		return nil, fmt.Errorf("image %s: %w", imagePath, fs.ErrNotExist)
+	}
+
+	width = snapWidth(width)
+	if width <= 0 || width >= img.Width {
+		data, err := fs.ReadFile(receiver.root, imagePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read image %s: %w", imagePath, err)
+		}
+		return &Variant{Data: data, ContentType: img.ContentType, Width: img.Width, Height: img.Height}, nil
+	}
+
+	cacheKey := imagePath + "?w=" + strconv.Itoa(width)
+	if variant, ok := receiver.variants.Get(cacheKey); ok {
+		return variant, nil
+	}
+
+	variant, err := receiver.resize(img, width)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resize image %s: %w", imagePath, err)
+	}
+	receiver.variants.Set(cacheKey, variant)
+
+	return variant, nil
+}
+
+func (receiver *ImageStore) resize(img *Image, width int) (*Variant, error) {
+	file, err := receiver.root.Open(img.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	source, _, err := image.Decode(file)
+	if err != nil {
+		return nil, err
+	}
+
+	height := img.Height * width / img.Width
+	if height < 1 {
+		height = 1
+	}
+	resized := downscale(source, width, height)
+
+	var buf bytes.Buffer
+	contentType := img.ContentType
+	switch contentType {
+	case "image/jpeg":
+		err = jpeg.Encode(&buf, resized, &jpeg.Options{Quality: 85})
+	default:
+		contentType = "image/png"
+		err = png.Encode(&buf, resized)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Variant{Data: buf.Bytes(), ContentType: contentType, Width: width, Height: height}, nil
+}
+
+func snapWidth(width int) int {
+	if width <= 0 {
+		return 0
+	}
+	index := sort.SearchInts(VariantWidths, width)
+	if index >= len(VariantWidths) {
+		return 0
+	}
+	return VariantWidths[index]
+}
+
+// downscale averages every source pixel covered by the destination pixel (box filter),
+// which gives decent results for shrinking screenshots without pulling in an imaging library.
+func downscale(source image.Image, width, height int) *image.NRGBA {
+	bounds := source.Bounds()
+	result := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		top := bounds.Min.Y + y*bounds.Dy()/height
+		bottom := bounds.Min.Y + (y+1)*bounds.Dy()/height
+		if bottom <= top {
+			bottom = top + 1
+		}
+
+		for x := 0; x < width; x++ {
+			left := bounds.Min.X + x*bounds.Dx()/width
+			right := bounds.Min.X + (x+1)*bounds.Dx()/width
+			if right <= left {
+				right = left + 1
+			}
+
+			var r, g, b, a, count uint64
+			for sy := top; sy < bottom; sy++ {
+				for sx := left; sx < right; sx++ {
+					pixel := color.NRGBA64Model.Convert(source.At(sx, sy)).(color.NRGBA64)
+					r += uint64(pixel.R)
+					g += uint64(pixel.G)
+					b += uint64(pixel.B)
+					a += uint64(pixel.A)
+					count++
+				}
+			}
+
+			result.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(r / count >> 8),
+				G: uint8(g / count >> 8),
+				B: uint8(b / count >> 8),
+				A: uint8(a / count >> 8),
+			})
+		}
+	}
+
+	return result
+}
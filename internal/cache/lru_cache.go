@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type lruEntry[T any] struct {
+	key string
+	entry[T]
+}
+
+// LRUCache keeps at most size items, the least recently used item is evicted when a new one doesn't fit.
+// Items also expire after the ttl like in TTLCache.
+type LRUCache[T any] struct {
+	mu    sync.Mutex
+	items map[string]*list.Element
+	order *list.List
+	size  int
+	ttl   time.Duration
+}
+
+func NewLRU[T any](size int, ttl time.Duration) *LRUCache[T] {
+	return &LRUCache[T]{
+		items: make(map[string]*list.Element),
+		order: list.New(),
+		size:  max(size, 1),
+		ttl:   ttl,
+	}
+}
+
+func (receiver *LRUCache[T]) Get(key string) (T, bool) {
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+
+	element, ok := receiver.items[key]
+	if !ok {
+		var zero T
+		return zero, false
+	}
+
+	item := element.Value.(*lruEntry[T])
+	if time.Now().After(item.expiresAt) {
+		receiver.remove(element)
+		var zero T
+		return zero, false
+	}
+
+	receiver.order.MoveToFront(element)
+	return item.value, true
+}
+
+func (receiver *LRUCache[T]) Set(key string, value T) {
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+
+	expiresAt := time.Now().Add(receiver.ttl)
+	if element, ok := receiver.items[key]; ok {
+		element.Value.(*lruEntry[T]).entry = entry[T]{value: value, expiresAt: expiresAt}
+		receiver.order.MoveToFront(element)
+		return
+	}
+
+	receiver.items[key] = receiver.order.PushFront(&lruEntry[T]{
+		key:   key,
+		entry: entry[T]{value: value, expiresAt: expiresAt},
+	})
+	for receiver.order.Len() > receiver.size {
+		receiver.remove(receiver.order.Back())
+	}
+}
+
+func (receiver *LRUCache[T]) remove(element *list.Element) {
+	receiver.order.Remove(element)
+	delete(receiver.items, element.Value.(*lruEntry[T]).key)
+}
@@ -0,0 +1,130 @@
+package clientinfo
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"SfosBeginnerGuide/internal/version"
+)
+
+const (
+	AppVersionHeader      = "X-App-Version"
+	SailfishVersionHeader = "X-Sailfish-Version"
+	ArchitectureHeader    = "X-Device-Architecture"
+	DeviceHeader          = "X-Device"
+	DeviceQueryParameter  = "device"
+
+	// VaryHeaders lists every header that influences the client info, for use in the Vary response header.
+	VaryHeaders = AppVersionHeader + ", " + SailfishVersionHeader + ", " + ArchitectureHeader + ", " + DeviceHeader + ", User-Agent"
+
+	userAgentProduct = "SfosBeginnerGuide/"
+)
+
+var knownArchitectures = []string{"aarch64", "armv7hl", "i486", "x86_64"}
+
+type contextKey struct{}
+
+// Info describes the client making the request. Every field is optional, a zero value means
+// the client did not tell us.
+type Info struct {
+	AppVersion      version.Version `json:"appVersion,omitempty"`
+	SailfishVersion version.Version `json:"sailfishVersion,omitempty"`
+	Architecture    string          `json:"architecture,omitempty"`
+	Device          string          `json:"device,omitempty"`
+}
+
+// FromRequest reads the client info from the dedicated headers, falling back to the app's user agent
+// which looks like `SfosBeginnerGuide/1.2.0 (SailfishOS 4.6.0.13; aarch64)`. The device can also be
+// selected using the device query parameter, which takes precedence over the header.
+func FromRequest(request *http.Request) Info {
+	info := parseUserAgent(request.Header.Get("User-Agent"))
+
+	if parsed, err := version.Parse(request.Header.Get(AppVersionHeader)); err == nil {
+		info.AppVersion = parsed
+	}
+	if parsed, err := version.Parse(request.Header.Get(SailfishVersionHeader)); err == nil {
+		info.SailfishVersion = parsed
+	}
+	if architecture := normalizeArchitecture(request.Header.Get(ArchitectureHeader)); architecture != "" {
+		info.Architecture = architecture
+	}
+	if device := normalizeDevice(request.URL.Query().Get(DeviceQueryParameter)); device != "" {
+		info.Device = device
+	} else {
+		info.Device = normalizeDevice(request.Header.Get(DeviceHeader))
+	}
+
+	return info
+}
+
+func WithInfo(ctx context.Context, info Info) context.Context {
+	return context.WithValue(ctx, contextKey{}, info)
+}
+
+func FromContext(ctx context.Context) Info {
+	info, _ := ctx.Value(contextKey{}).(Info)
+	return info
+}
+
+// Middleware stores the client info of every request in its context.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		next.ServeHTTP(writer, request.WithContext(WithInfo(request.Context(), FromRequest(request))))
+	})
+}
+
+func parseUserAgent(userAgent string) Info {
+	var info Info
+
+	start := strings.Index(userAgent, userAgentProduct)
+	if start < 0 {
+		return info
+	}
+	rest := userAgent[start+len(userAgentProduct):]
+	appVersion, rest, _ := strings.Cut(rest, " ")
+	if parsed, err := version.Parse(appVersion); err == nil {
+		info.AppVersion = parsed
+	}
+
+	open := strings.Index(rest, "(")
+	closing := strings.Index(rest, ")")
+	if open < 0 || closing < open {
+		return info
+	}
+	for _, part := range strings.Split(rest[open+1:closing], ";") {
+		part = strings.TrimSpace(part)
+		if name, value, found := strings.Cut(part, " "); found && strings.HasPrefix(strings.ToLower(name), "sailfish") {
+			if parsed, err := version.Parse(value); err == nil {
+				info.SailfishVersion = parsed
+			}
+			continue
+		}
+		if architecture := normalizeArchitecture(part); architecture != "" {
+			info.Architecture = architecture
+		}
+	}
+
+	return info
+}
+
+func normalizeArchitecture(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	for _, known := range knownArchitectures {
+		if value == known {
+			return known
+		}
+	}
+	return ""
+}
+
+// normalizeDevice lowercases the device id and rejects anything that could not be part of a file name.
+func normalizeDevice(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	for _, char := range value {
+		if (char < 'a' || char > 'z') && (char < '0' || char > '9') && char != '-' {
+			return ""
+		}
+	}
+	return value
+}
@@ -14,6 +14,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 const (
@@ -97,7 +99,8 @@ func main() {
 			return fmt.Errorf("read %s: %w", path, err)
 		}
 
-		text := strings.TrimSpace(stripFrontMatter(string(content)))
+		frontMatter, body := splitFrontMatter(string(content))
+		text := strings.TrimSpace(body)
 		if text == "" {
 			fmt.Printf("Embedding %s (empty after front matter)\n", path)
 			return writeEmbeddings(path, model, 0, nil)
@@ -105,7 +108,7 @@ func main() {
 
 		chunks := chunkText(text, maxWordsPerChunk, overlapWords)
 		fmt.Printf("Embedding %s (%d chunks)\n", path, len(chunks))
-		vectors, dim, err := embedChunks(baseURL, chunks)
+		vectors, dim, err := embedChunks(baseURL, withMetadata(frontMatter, chunks))
 		if err != nil {
 			return fmt.Errorf("embed %s: %w", path, err)
 		}
@@ -198,21 +201,52 @@ func waitForHealth(baseURL string, timeout time.Duration) error {
 	return errors.New("timeout waiting for /health")
 }
 
-func stripFrontMatter(input string) string {
+func splitFrontMatter(input string) (frontMatter string, body string) {
 	lines := strings.Split(input, "\n")
 	if len(lines) == 0 {
-		return input
+		return "", input
 	}
 	if strings.TrimSpace(lines[0]) != "---" {
-		return input
+		return "", input
 	}
 	for i := 1; i < len(lines); i++ {
 		line := strings.TrimSpace(lines[i])
 		if line == "---" || line == "..." {
-			return strings.Join(lines[i+1:], "\n")
+			return strings.Join(lines[1:i], "\n"), strings.Join(lines[i+1:], "\n")
 		}
 	}
-	return input
+	return "", input
+}
+
+// withMetadata prefixes every chunk with the title, description, keywords and tags of the page,
+// so that pages are also found by the words of their metadata.
+func withMetadata(frontMatter string, chunks []string) []string {
+	var meta struct {
+		Title       string   `yaml:"title"`
+		Description string   `yaml:"description"`
+		Keywords    []string `yaml:"keywords"`
+		Tags        []string `yaml:"tags"`
+	}
+	if err := yaml.Unmarshal([]byte(frontMatter), &meta); err != nil {
+		return chunks
+	}
+
+	var parts []string
+	for _, part := range []string{meta.Title, meta.Description, strings.Join(meta.Keywords, ", "), strings.Join(meta.Tags, ", ")} {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return chunks
+	}
+
+	prefix := strings.Join(parts, "\n") + "\n\n"
+	result := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		result[i] = prefix + chunk
+	}
+	return result
 }
 
 func chunkText(text string, maxWords int, overlap int) []string {
@@ -0,0 +1,152 @@
+package condition
+
+import (
+	"fmt"
+	"strings"
+
+	"SfosBeginnerGuide/internal/clientinfo"
+	"SfosBeginnerGuide/internal/version"
+)
+
+const (
+	KeySailfish     = "sfos"
+	KeyApp          = "app"
+	KeyArchitecture = "arch"
+	KeyDevice       = "device"
+)
+
+// operators are ordered so that the two character ones are tried first
+var operators = []string{">=", "<=", "!=", "==", ">", "<", "="}
+
+type Condition struct {
+	Key      string
+	Operator string
+	Value    string
+
+	version version.Version
+}
+
+// Expression is a comma separated list of conditions which all must match, for example `sfos>=5.0, arch==aarch64`.
+type Expression []Condition
+
+func Parse(raw string) (Expression, error) {
+	var result Expression
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		condition, err := parseCondition(part)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, condition)
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("empty condition")
+	}
+	return result, nil
+}
+
+func parseCondition(raw string) (Condition, error) {
+	for _, operator := range operators {
+		key, value, found := strings.Cut(raw, operator)
+		if !found {
+			continue
+		}
+
+		condition := Condition{
+			Key:      strings.ToLower(strings.TrimSpace(key)),
+			Operator: operator,
+			Value:    strings.TrimSpace(value),
+		}
+		if condition.Operator == "=" {
+			condition.Operator = "=="
+		}
+		if condition.Value == "" {
+			return Condition{}, fmt.Errorf("missing value in condition %q", raw)
+		}
+
+		switch condition.Key {
+		case KeySailfish, KeyApp:
+			parsed, err := version.Parse(condition.Value)
+			if err != nil {
+				return Condition{}, fmt.Errorf("condition %q: %w", raw, err)
+			}
+			condition.version = parsed
+		case KeyArchitecture, KeyDevice:
+			if condition.Operator != "==" && condition.Operator != "!=" {
+				return Condition{}, fmt.Errorf("condition %q: %s only supports == and !=", raw, condition.Key)
+			}
+		default:
+			return Condition{}, fmt.Errorf("unknown key %q in condition %q", condition.Key, raw)
+		}
+
+		return condition, nil
+	}
+
+	return Condition{}, fmt.Errorf("missing operator in condition %q", raw)
+}
+
+// Evaluate checks the expression against the client. If the client did not send some of the information
+// the expression depends on, known is false and the result must not be used to hide anything.
+func (receiver Expression) Evaluate(client clientinfo.Info) (matched bool, known bool) {
+	matched, known = true, true
+	for _, condition := range receiver {
+		conditionMatched, conditionKnown := condition.evaluate(client)
+		if !conditionKnown {
+			known = false
+			continue
+		}
+		if !conditionMatched {
+			return false, true
+		}
+	}
+	return matched, known
+}
+
+func (receiver Condition) evaluate(client clientinfo.Info) (bool, bool) {
+	switch receiver.Key {
+	case KeySailfish:
+		return compareVersions(client.SailfishVersion, receiver.version, receiver.Operator)
+	case KeyApp:
+		return compareVersions(client.AppVersion, receiver.version, receiver.Operator)
+	case KeyArchitecture:
+		return compareStrings(client.Architecture, receiver.Value, receiver.Operator)
+	case KeyDevice:
+		return compareStrings(client.Device, receiver.Value, receiver.Operator)
+	}
+	return false, false
+}
+
+func compareStrings(actual string, expected string, operator string) (bool, bool) {
+	if actual == "" {
+		return false, false
+	}
+	equal := strings.EqualFold(actual, expected)
+	return equal == (operator == "=="), true
+}
+
+func compareVersions(actual version.Version, expected version.Version, operator string) (bool, bool) {
+	if actual == nil {
+		return false, false
+	}
+
+	comparison := actual.Compare(expected)
+	switch operator {
+	case ">=":
+		return comparison >= 0, true
+	case "<=":
+		return comparison <= 0, true
+	case ">":
+		return comparison > 0, true
+	case "<":
+		return comparison < 0, true
+	case "!=":
+		return comparison != 0, true
+	default:
+		return comparison == 0, true
+	}
+}
@@ -0,0 +1,286 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	Port             int           `toml:"port" yaml:"port" json:"port"`
+	LogLevel         slog.Level    `toml:"log_level" yaml:"log_level" json:"logLevel"`
+	Cache            CacheConfig   `toml:"cache" yaml:"cache" json:"cache"`
+	Search           SearchConfig  `toml:"search" yaml:"search" json:"search"`
+	Tracing          TracingConfig `toml:"tracing" yaml:"tracing" json:"tracing"`
+	OfflineBundleURL string        `toml:"offline_bundle_url" yaml:"offline_bundle_url" json:"offlineBundleUrl"`
+	FeedbackURL      string        `toml:"feedback_url" yaml:"feedback_url" json:"feedbackUrl"`
+}
+
+type CacheConfig struct {
+	ContentTTL Duration `toml:"content_ttl" yaml:"content_ttl" json:"contentTtl"`
+	// ContentEntries is the maximum number of rendered pages kept in memory, every page is rendered
+	// separately for every combination of format and client
+	ContentEntries int      `toml:"content_entries" yaml:"content_entries" json:"contentEntries"`
+	ImageTTL       Duration `toml:"image_ttl" yaml:"image_ttl" json:"imageTtl"`
+	// ImageEntries is the maximum number of resized images kept in memory
+	ImageEntries int `toml:"image_entries" yaml:"image_entries" json:"imageEntries"`
+}
+
+type SearchConfig struct {
+	Enabled           bool     `toml:"enabled" yaml:"enabled" json:"enabled"`
+	EmbeddingsServer  string   `toml:"embeddings_server" yaml:"embeddings_server" json:"embeddingsServer"`
+	EmbeddingsTimeout Duration `toml:"embeddings_timeout" yaml:"embeddings_timeout" json:"embeddingsTimeout"`
+	Rerank            bool     `toml:"rerank" yaml:"rerank" json:"rerank"`
+	LexicalFallback   bool     `toml:"lexical_fallback" yaml:"lexical_fallback" json:"lexicalFallback"`
+	Timeout           Duration `toml:"timeout" yaml:"timeout" json:"timeout"`
+	DefaultResults    int      `toml:"default_results" yaml:"default_results" json:"defaultResults"`
+	MaxResults        int      `toml:"max_results" yaml:"max_results" json:"maxResults"`
+	// CacheMaxAge is how long GET searches may be cached. With analytics they may only be cached by the client,
+	// as searches answered by a shared cache (a CDN or proxy) would never be recorded.
+	CacheMaxAge Duration `toml:"cache_max_age" yaml:"cache_max_age" json:"cacheMaxAge"`
+	// MinScore is the cosine similarity below which documents are not considered relevant to the query,
+	// the right value depends on the embedding model
+	MinScore float64 `toml:"min_score" yaml:"min_score" json:"minScore"`
+	// MaxCandidates is the maximum number of relevant documents, the filters, facets and totals only see these
+	MaxCandidates int `toml:"max_candidates" yaml:"max_candidates" json:"maxCandidates"`
+	// AnalyticsFile is the JSONL file the anonymized searches are appended to, empty disables the analytics
+	AnalyticsFile string `toml:"analytics_file" yaml:"analytics_file" json:"analyticsFile"`
+}
+
+const (
+	TracingNone   = "none"
+	TracingStdout = "stdout"
+	TracingOTLP   = "otlp"
+)
+
+type TracingConfig struct {
+	// Exporter is one of none, stdout or otlp
+	Exporter    string `toml:"exporter" yaml:"exporter" json:"exporter"`
+	Endpoint    string `toml:"endpoint" yaml:"endpoint" json:"endpoint"`
+	ServiceName string `toml:"service_name" yaml:"service_name" json:"serviceName"`
+}
+
+// Duration is a time.Duration written as a string like "5m" in config files.
+type Duration time.Duration
+
+func (receiver Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(receiver).String()), nil
+}
+
+func (receiver *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(strings.TrimSpace(string(text)))
+	if err != nil {
+		return err
+	}
+	*receiver = Duration(parsed)
+	return nil
+}
+
+func (receiver Duration) Std() time.Duration {
+	return time.Duration(receiver)
+}
+
+func Default() *Config {
+	return &Config{
+		Port:     8080,
+		LogLevel: slog.LevelInfo,
+		Cache: CacheConfig{
+			ContentTTL:     Duration(5 * time.Minute),
+			ContentEntries: 2000,
+			ImageTTL:       Duration(time.Hour),
+			ImageEntries:   500,
+		},
+		Search: SearchConfig{
+			EmbeddingsTimeout: Duration(60 * time.Second),
+			Timeout:           Duration(2 * time.Minute),
+			DefaultResults:    20,
+			MaxResults:        100,
+			CacheMaxAge:       Duration(5 * time.Minute),
+			MinScore:          0.5,
+			MaxCandidates:     200,
+		},
+		Tracing: TracingConfig{
+			Exporter:    TracingNone,
+			ServiceName: "sfos-beginner-guide",
+		},
+	}
+}
+
+// Load reads the configuration once at startup: the defaults are overridden by the optional TOML or YAML
+// file pointed to by CONFIG_FILE and environment variables override whatever the file contains.
+// Invalid values are reported as errors instead of being silently replaced by defaults.
+func Load() (*Config, error) {
+	cfg := Default()
+
+	if file := strings.TrimSpace(os.Getenv("CONFIG_FILE")); file != "" {
+		if err := loadFile(file, cfg); err != nil {
+			return nil, fmt.Errorf("failed to load config file %s: %w", file, err)
+		}
+	}
+
+	env := &envReader{}
+	env.int("APP_PORT", &cfg.Port)
+	env.text("LOG_LEVEL", &cfg.LogLevel)
+	env.duration("CONTENT_CACHE_TTL", &cfg.Cache.ContentTTL)
+	env.int("CONTENT_CACHE_ENTRIES", &cfg.Cache.ContentEntries)
+	env.duration("IMAGE_CACHE_TTL", &cfg.Cache.ImageTTL)
+	env.int("IMAGE_CACHE_ENTRIES", &cfg.Cache.ImageEntries)
+	env.bool("CAPABILITY_SEARCHING", &cfg.Search.Enabled)
+	env.string("EMBEDDINGS_SERVER", &cfg.Search.EmbeddingsServer)
+	env.duration("EMBEDDINGS_TIMEOUT", &cfg.Search.EmbeddingsTimeout)
+	env.bool("CAPABILITY_RERANK", &cfg.Search.Rerank)
+	env.bool("CAPABILITY_LEXICAL_FALLBACK", &cfg.Search.LexicalFallback)
+	env.duration("SEARCH_TIMEOUT", &cfg.Search.Timeout)
+	env.int("SEARCH_DEFAULT_RESULTS", &cfg.Search.DefaultResults)
+	env.int("SEARCH_MAX_RESULTS", &cfg.Search.MaxResults)
+	env.duration("SEARCH_CACHE_MAX_AGE", &cfg.Search.CacheMaxAge)
+	env.float("SEARCH_MIN_SCORE", &cfg.Search.MinScore)
+	env.int("SEARCH_MAX_CANDIDATES", &cfg.Search.MaxCandidates)
+	env.string("SEARCH_ANALYTICS_FILE", &cfg.Search.AnalyticsFile)
+	env.string("OFFLINE_BUNDLE_URL", &cfg.OfflineBundleURL)
+	env.string("FEEDBACK_URL", &cfg.FeedbackURL)
+	env.string("TRACING_EXPORTER", &cfg.Tracing.Exporter)
+	env.string("OTEL_EXPORTER_OTLP_ENDPOINT", &cfg.Tracing.Endpoint)
+	env.string("OTEL_SERVICE_NAME", &cfg.Tracing.ServiceName)
+	if err := errors.Join(env.errs...); err != nil {
+		return nil, fmt.Errorf("invalid environment: %w", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return cfg, nil
+}
+
+func loadFile(file string, cfg *Config) error {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".toml":
+		metadata, err := toml.DecodeFile(file, cfg)
+		if err != nil {
+			return err
+		}
+		if undecoded := metadata.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("unknown key %s", undecoded[0])
+		}
+		return nil
+	case ".yaml", ".yml":
+		raw, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer raw.Close()
+
+		decoder := yaml.NewDecoder(raw)
+		decoder.KnownFields(true)
+		if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported config file type %q (expected .toml, .yaml or .yml)", filepath.Ext(file))
+	}
+}
+
+func (receiver *Config) Validate() error {
+	var errs []error
+
+	if receiver.Port < 1 || receiver.Port > 65535 {
+		errs = append(errs, fmt.Errorf("port must be between 1 and 65535, got %d", receiver.Port))
+	}
+	if receiver.Cache.ContentTTL <= 0 {
+		errs = append(errs, errors.New("cache.content_ttl must be positive"))
+	}
+	if receiver.Cache.ContentEntries < 1 {
+		errs = append(errs, fmt.Errorf("cache.content_entries must be at least 1, got %d", receiver.Cache.ContentEntries))
+	}
+	if receiver.Cache.ImageTTL <= 0 {
+		errs = append(errs, errors.New("cache.image_ttl must be positive"))
+	}
+	if receiver.Cache.ImageEntries < 1 {
+		errs = append(errs, fmt.Errorf("cache.image_entries must be at least 1, got %d", receiver.Cache.ImageEntries))
+	}
+	if receiver.Search.EmbeddingsServer != "" {
+		if err := validateURL(receiver.Search.EmbeddingsServer); err != nil {
+			errs = append(errs, fmt.Errorf("search.embeddings_server: %w", err))
+		}
+	}
+	if receiver.Search.EmbeddingsTimeout <= 0 {
+		errs = append(errs, errors.New("search.embeddings_timeout must be positive"))
+	}
+	if receiver.Search.Timeout <= 0 {
+		errs = append(errs, errors.New("search.timeout must be positive"))
+	}
+	if receiver.Search.MaxResults < 1 {
+		errs = append(errs, fmt.Errorf("search.max_results must be at least 1, got %d", receiver.Search.MaxResults))
+	}
+	if receiver.Search.DefaultResults < 1 || receiver.Search.DefaultResults > receiver.Search.MaxResults {
+		errs = append(errs, fmt.Errorf(
+			"search.default_results must be between 1 and search.max_results (%d), got %d",
+			receiver.Search.MaxResults,
+			receiver.Search.DefaultResults,
+		))
+	}
+	if receiver.Search.MinScore < -1 || receiver.Search.MinScore > 1 {
+		errs = append(errs, fmt.Errorf("search.min_score must be between -1 and 1, got %g", receiver.Search.MinScore))
+	}
+	if receiver.Search.MaxCandidates < receiver.Search.MaxResults {
+		errs = append(errs, fmt.Errorf(
+			"search.max_candidates must be at least search.max_results (%d), got %d",
+			receiver.Search.MaxResults,
+			receiver.Search.MaxCandidates,
+		))
+	}
+	if receiver.Search.CacheMaxAge < 0 {
+		errs = append(errs, errors.New("search.cache_max_age must not be negative"))
+	}
+	if receiver.OfflineBundleURL != "" {
+		if err := validateURL(receiver.OfflineBundleURL); err != nil {
+			errs = append(errs, fmt.Errorf("offline_bundle_url: %w", err))
+		}
+	}
+	if receiver.FeedbackURL != "" {
+		if err := validateURL(receiver.FeedbackURL); err != nil {
+			errs = append(errs, fmt.Errorf("feedback_url: %w", err))
+		}
+	}
+	switch receiver.Tracing.Exporter {
+	case TracingNone, TracingStdout:
+	case TracingOTLP:
+		if err := validateURL(receiver.Tracing.Endpoint); err != nil {
+			errs = append(errs, fmt.Errorf("tracing.endpoint: %w", err))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("tracing.exporter must be one of none, stdout or otlp, got %q", receiver.Tracing.Exporter))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Print writes the effective configuration in the TOML format, so that it can be used as a config file.
+func (receiver *Config) Print(writer io.Writer) error {
+	return toml.NewEncoder(writer).Encode(receiver)
+}
+
+func validateURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Errorf("expected an http or https URL, got %q", raw)
+	}
+	if parsed.Host == "" {
+		return fmt.Errorf("missing host in %q", raw)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// envReader overrides config values with environment variables, unlike defaults it remembers
+// values that could not be parsed so that they can be reported all at once.
+type envReader struct {
+	errs []error
+}
+
+func (receiver *envReader) lookup(name string) (string, bool) {
+	value, ok := os.LookupEnv(name)
+	if !ok || strings.TrimSpace(value) == "" {
+		return "", false
+	}
+	return strings.TrimSpace(value), true
+}
+
+func (receiver *envReader) string(name string, target *string) {
+	if value, ok := receiver.lookup(name); ok {
+		*target = value
+	}
+}
+
+func (receiver *envReader) bool(name string, target *bool) {
+	value, ok := receiver.lookup(name)
+	if !ok {
+		return
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		receiver.errs = append(receiver.errs, fmt.Errorf("%s: expected a boolean, got %q", name, value))
+		return
+	}
+	*target = parsed
+}
+
+func (receiver *envReader) int(name string, target *int) {
+	value, ok := receiver.lookup(name)
+	if !ok {
+		return
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		receiver.errs = append(receiver.errs, fmt.Errorf("%s: expected an integer, got %q", name, value))
+		return
+	}
+	*target = parsed
+}
+
+func (receiver *envReader) float(name string, target *float64) {
+	value, ok := receiver.lookup(name)
+	if !ok {
+		return
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		receiver.errs = append(receiver.errs, fmt.Errorf("%s: expected a number, got %q", name, value))
+		return
+	}
+	*target = parsed
+}
+
+func (receiver *envReader) duration(name string, target *Duration) {
+	value, ok := receiver.lookup(name)
+	if !ok {
+		return
+	}
+	var parsed Duration
+	if err := parsed.UnmarshalText([]byte(value)); err != nil {
+		receiver.errs = append(receiver.errs, fmt.Errorf("%s: expected a duration like 5m, got %q", name, value))
+		return
+	}
+	*target = parsed
+}
+
+func (receiver *envReader) text(name string, target encoding.TextUnmarshaler) {
+	value, ok := receiver.lookup(name)
+	if !ok {
+		return
+	}
+	if err := target.UnmarshalText([]byte(value)); err != nil {
+		receiver.errs = append(receiver.errs, fmt.Errorf("%s: %w", name, err))
+	}
+}
@@ -0,0 +1,215 @@
+package content
+
+import (
+	"fmt"
+	"io/fs"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"SfosBeginnerGuide/internal/version"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	ParameterString = "string"
+	ParameterInt    = "int"
+	ParameterBool   = "bool"
+)
+
+type ActionParameter struct {
+	Name        string `yaml:"name" json:"name"`
+	Type        string `yaml:"type" json:"type"`
+	Required    bool   `yaml:"required" json:"required"`
+	Description string `yaml:"description" json:"description,omitempty"`
+}
+
+type ActionDefinition struct {
+	ID            string             `yaml:"-" json:"id"`
+	Description   string             `yaml:"description" json:"description,omitempty"`
+	MinAppVersion string             `yaml:"minAppVersion" json:"minAppVersion,omitempty"`
+	Parameters    []*ActionParameter `yaml:"parameters" json:"parameters,omitempty"`
+
+	minAppVersion version.Version
+}
+
+// ActionRef is a reference to an action as written in a page, the id optionally followed
+// by parameters in query string form, like `some-action?name=value`.
+type ActionRef struct {
+	ID     string
+	Params url.Values
+}
+
+func ParseActionRef(raw string) (ActionRef, error) {
+	id, query, _ := strings.Cut(strings.TrimSpace(raw), "?")
+	if id == "" {
+		return ActionRef{}, fmt.Errorf("empty action id in %q", raw)
+	}
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return ActionRef{}, fmt.Errorf("invalid parameters of action %s: %w", id, err)
+	}
+	return ActionRef{ID: id, Params: params}, nil
+}
+
+type ActionRegistry struct {
+	actions map[string]*ActionDefinition
+}
+
+func LoadActionRegistry(root fs.FS, registryPath string) (*ActionRegistry, error) {
+	data, err := fs.ReadFile(root, registryPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read action registry %s: %w", registryPath, err)
+	}
+
+	var file struct {
+		Actions map[string]*ActionDefinition `yaml:"actions"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse action registry %s: %w", registryPath, err)
+	}
+
+	registry := &ActionRegistry{actions: make(map[string]*ActionDefinition, len(file.Actions))}
+	for id, definition := range file.Actions {
+		if definition == nil {
+			definition = &ActionDefinition{}
+		}
+		definition.ID = id
+		if definition.MinAppVersion != "" {
+			definition.minAppVersion, err = version.Parse(definition.MinAppVersion)
+			if err != nil {
+				return nil, fmt.Errorf("action %s: %w", id, err)
+			}
+		}
+		for _, parameter := range definition.Parameters {
+			switch parameter.Type {
+			case "":
+				parameter.Type = ParameterString
+			case ParameterString, ParameterInt, ParameterBool:
+			default:
+				return nil, fmt.Errorf("action %s: unknown type %s of parameter %s", id, parameter.Type, parameter.Name)
+			}
+		}
+		registry.actions[id] = definition
+	}
+
+	return registry, nil
+}
+
+func (receiver *ActionRegistry) Get(id string) (*ActionDefinition, bool) {
+	definition, ok := receiver.actions[id]
+	return definition, ok
+}
+
+func (receiver *ActionRegistry) List() []*ActionDefinition {
+	result := make([]*ActionDefinition, 0, len(receiver.actions))
+	for _, definition := range receiver.actions {
+		result = append(result, definition)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
+
+// Validate checks that the referenced action exists and that its parameters match the declared ones.
+func (receiver *ActionRegistry) Validate(rawRef string) error {
+	ref, err := ParseActionRef(rawRef)
+	if err != nil {
+		return err
+	}
+	definition, ok := receiver.Get(ref.ID)
+	if !ok {
+		return fmt.Errorf("unknown action %s", ref.ID)
+	}
+
+	declared := make(map[string]struct{}, len(definition.Parameters))
+	for _, parameter := range definition.Parameters {
+		declared[parameter.Name] = struct{}{}
+		value := ref.Params.Get(parameter.Name)
+		if value == "" {
+			if parameter.Required {
+				return fmt.Errorf("action %s: missing required parameter %s", ref.ID, parameter.Name)
+			}
+			continue
+		}
+		if err := validateParameterValue(parameter, value); err != nil {
+			return fmt.Errorf("action %s: %w", ref.ID, err)
+		}
+	}
+	for name := range ref.Params {
+		if _, ok := declared[name]; !ok {
+			return fmt.Errorf("action %s: unknown parameter %s", ref.ID, name)
+		}
+	}
+
+	return nil
+}
+
+// Supports reports whether the given app version can perform the referenced action.
+// Unknown app versions are assumed to support everything.
+func (receiver *ActionRegistry) Supports(rawRef string, appVersion version.Version) bool {
+	ref, err := ParseActionRef(rawRef)
+	if err != nil {
+		return false
+	}
+	definition, ok := receiver.Get(ref.ID)
+	if !ok {
+		return false
+	}
+	if appVersion == nil || definition.minAppVersion == nil {
+		return true
+	}
+	return appVersion.Compare(definition.minAppVersion) >= 0
+}
+
+// SupportedActions lists the ids of all actions the given app version can perform.
+func (receiver *ActionRegistry) SupportedActions(appVersion version.Version) []string {
+	result := make([]string, 0, len(receiver.actions))
+	for _, definition := range receiver.List() {
+		if receiver.Supports(definition.ID, appVersion) {
+			result = append(result, definition.ID)
+		}
+	}
+	return result
+}
+
+// Filter returns a copy of the item whose actions are limited to the ones the app version supports,
+// the rest is listed in Meta.UnsupportedActions. The original (possibly cached) item is never modified.
+func (receiver *ActionRegistry) Filter(item *Item, appVersion version.Version) *Item {
+	if appVersion == nil || len(item.Meta.Actions) == 0 {
+		return item
+	}
+
+	meta := *item.Meta
+	meta.Actions = nil
+	meta.UnsupportedActions = nil
+	for _, action := range item.Meta.Actions {
+		if receiver.Supports(action, appVersion) {
+			meta.Actions = append(meta.Actions, action)
+		} else {
+			meta.UnsupportedActions = append(meta.UnsupportedActions, action)
+		}
+	}
+	if meta.Actions == nil {
+		meta.Actions = []string{}
+	}
+
+	filtered := *item
+	filtered.Meta = &meta
+	return &filtered
+}
+
+func validateParameterValue(parameter *ActionParameter, value string) error {
+	var err error
+	switch parameter.Type {
+	case ParameterInt:
+		_, err = strconv.Atoi(value)
+	case ParameterBool:
+		_, err = strconv.ParseBool(value)
+	}
+	if err != nil {
+		return fmt.Errorf("parameter %s must be of type %s, got %q", parameter.Name, parameter.Type, value)
+	}
+	return nil
+}
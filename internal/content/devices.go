@@ -0,0 +1,116 @@
+package content
+
+import (
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const overlayInfix = ".device-"
+
+type Device struct {
+	ID           string `yaml:"id" json:"id"`
+	Name         string `yaml:"name" json:"name"`
+	Architecture string `yaml:"architecture" json:"architecture,omitempty"`
+}
+
+type DeviceRegistry struct {
+	devices []*Device
+	byID    map[string]*Device
+}
+
+func LoadDeviceRegistry(root fs.FS, registryPath string) (*DeviceRegistry, error) {
+	data, err := fs.ReadFile(root, registryPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read device registry %s: %w", registryPath, err)
+	}
+
+	var file struct {
+		Devices []*Device `yaml:"devices"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse device registry %s: %w", registryPath, err)
+	}
+
+	registry := &DeviceRegistry{devices: file.Devices, byID: make(map[string]*Device, len(file.Devices))}
+	for _, device := range file.Devices {
+		if device.ID == "" {
+			return nil, fmt.Errorf("device registry %s: device without id", registryPath)
+		}
+		if _, exists := registry.byID[device.ID]; exists {
+			return nil, fmt.Errorf("device registry %s: duplicate device %s", registryPath, device.ID)
+		}
+		registry.byID[device.ID] = device
+	}
+
+	return registry, nil
+}
+
+func (receiver *DeviceRegistry) List() []*Device {
+	return receiver.devices
+}
+
+func (receiver *DeviceRegistry) Get(id string) (*Device, bool) {
+	device, ok := receiver.byID[id]
+	return device, ok
+}
+
+// OverlayPath returns the path of the device specific overlay of the given page.
+func OverlayPath(pagePath string, device string) string {
+	return strings.TrimSuffix(pagePath, ".md") + overlayInfix + device + ".md"
+}
+
+// ParseOverlayPath returns the base page and the device of an overlay path, ok is false for regular pages.
+func ParseOverlayPath(pagePath string) (basePath string, device string, ok bool) {
+	withoutExtension := strings.TrimSuffix(pagePath, ".md")
+	index := strings.LastIndex(withoutExtension, overlayInfix)
+	if index < 0 {
+		return "", "", false
+	}
+	return withoutExtension[:index] + ".md", withoutExtension[index+len(overlayInfix):], true
+}
+
+// mergeOverlay applies a device overlay on top of the base page: non-empty metadata and intro replace
+// the base ones, sections replace the base sections with the same title and the rest is appended.
+func mergeOverlay(base *Item, overlay *Item) *Item {
+	meta := *base.Meta
+	if overlay.Meta.Title != "" {
+		meta.Title = overlay.Meta.Title
+	}
+	if overlay.Meta.Description != "" {
+		meta.Description = overlay.Meta.Description
+	}
+	if len(overlay.Meta.Links) > 0 {
+		meta.Links = overlay.Meta.Links
+	}
+	meta.Actions = mergeActions(meta.Actions, overlay.Meta.Actions)
+
+	result := *base
+	result.Meta = &meta
+	if overlay.Content != "" || len(overlay.Blocks) > 0 {
+		result.Content = overlay.Content
+		result.Blocks = overlay.Blocks
+	}
+	if len(overlay.Links) > 0 {
+		result.Links = overlay.Links
+	}
+
+	result.Sections = append([]*Section{}, base.Sections...)
+	for _, section := range overlay.Sections {
+		replaced := false
+		for i, existing := range result.Sections {
+			if existing.Title == section.Title {
+				result.Sections[i] = section
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			result.Sections = append(result.Sections, section)
+		}
+	}
+
+	return &result
+}
@@ -1,24 +1,53 @@
 package content
 
+import "SfosBeginnerGuide/internal/markdown"
+
 type Link struct {
 	Link  string `json:"link"`
 	Title string `json:"title"`
 }
 
 type Section struct {
-	Title   string `json:"title"`
-	Content string `json:"content"`
+	Title   string            `json:"title"`
+	Content string            `json:"content"`
+	Blocks  []*markdown.Block `json:"blocks,omitempty"`
 }
 
+const (
+	DifficultyBeginner     = "beginner"
+	DifficultyIntermediate = "intermediate"
+	DifficultyAdvanced     = "advanced"
+)
+
+// LastReviewedLayout is the format of the lastReviewed front matter field.
+const LastReviewedLayout = "2006-01-02"
+
 type Meta struct {
-	Title   string   `yaml:"title" json:"title"`
-	Links   []string `yaml:"links" json:"links"`
-	Actions []string `yaml:"actions" json:"actions"`
+	Title        string   `yaml:"title" json:"title"`
+	Description  string   `yaml:"description" json:"description,omitempty"`
+	Links        []string `yaml:"links" json:"links"`
+	Actions      []string `yaml:"actions" json:"actions"`
+	Condition    string   `yaml:"condition" json:"condition,omitempty"`
+	Devices      []string `yaml:"devices" json:"devices,omitempty"`
+	Tags         []string `yaml:"tags" json:"tags,omitempty"`
+	Keywords     []string `yaml:"keywords" json:"keywords,omitempty"`
+	Aliases      []string `yaml:"aliases" json:"aliases,omitempty"`
+	Author       string   `yaml:"author" json:"author,omitempty"`
+	LastReviewed string   `yaml:"lastReviewed" json:"lastReviewed,omitempty"`
+	// Difficulty is one of beginner, intermediate or advanced
+	Difficulty string `yaml:"difficulty" json:"difficulty,omitempty"`
+
+	UnsupportedActions []string `yaml:"-" json:"unsupportedActions,omitempty"`
 }
 
 type Item struct {
-	Meta     *Meta      `json:"meta"`
-	Content  string     `json:"content"`
-	Sections []*Section `json:"sections,omitempty"`
-	Links    []*Link    `json:"links,omitempty"`
+	Meta *Meta `json:"meta"`
+	// NotApplicable is set when the page's condition does not match the client, links to such pages are hidden.
+	NotApplicable bool `json:"notApplicable,omitempty"`
+	// Overlay is the device specific file merged into the page, if any.
+	Overlay  string            `json:"overlay,omitempty"`
+	Content  string            `json:"content"`
+	Blocks   []*markdown.Block `json:"blocks,omitempty"`
+	Sections []*Section        `json:"sections,omitempty"`
+	Links    []*Link           `json:"links,omitempty"`
 }
@@ -0,0 +1,117 @@
+package content
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"SfosBeginnerGuide/internal/clientinfo"
+	"SfosBeginnerGuide/internal/condition"
+)
+
+type Format string
+
+const (
+	// FormatHTML renders the content and sections as HTML strings.
+	FormatHTML Format = "html"
+	// FormatQML renders HTML limited to what Qt's RichText text format can display.
+	FormatQML Format = "qml"
+	// FormatAST returns the content and sections as a typed block tree instead of HTML.
+	FormatAST Format = "ast"
+)
+
+func ParseFormat(value string) (Format, bool) {
+	switch Format(value) {
+	case "", FormatHTML:
+		return FormatHTML, true
+	case FormatQML:
+		return FormatQML, true
+	case FormatAST:
+		return FormatAST, true
+	}
+	return "", false
+}
+
+// Options influence how a single document is rendered. Pages are cached per format and per outcome of
+// the conditions they contain, see variation.
+type Options struct {
+	Format Format
+	Client clientinfo.Info
+}
+
+func (receiver Options) format() Format {
+	if receiver.Format == "" {
+		return FormatHTML
+	}
+	return receiver.Format
+}
+
+// variation is what a rendered page depends on besides its path and format: the conditions evaluated while
+// rendering it and whether it differs per device. It includes the pages it links to, as their titles and
+// applicability are part of it. Pages without conditions, overlays or device lists are rendered once per format.
+type variation struct {
+	conditions []parsedCondition
+	device     bool
+}
+
+type parsedCondition struct {
+	raw        string
+	expression condition.Expression
+}
+
+func (receiver *variation) addCondition(raw string, expression condition.Expression) {
+	if !slices.ContainsFunc(receiver.conditions, func(known parsedCondition) bool { return known.raw == raw }) {
+		receiver.conditions = append(receiver.conditions, parsedCondition{raw: raw, expression: expression})
+	}
+}
+
+// merge returns a variation depending on everything both depend on, neither of them is modified.
+func (receiver *variation) merge(other *variation) *variation {
+	result := &variation{}
+	for _, current := range []*variation{receiver, other} {
+		if current == nil {
+			continue
+		}
+		for _, known := range current.conditions {
+			result.addCondition(known.raw, known.expression)
+		}
+		result.device = result.device || current.device
+	}
+	return result
+}
+
+// cacheKey identifies the rendering of the page for the client. The conditions are part of the key, so
+// keys computed before more conditions were known never match a rendering depending on them.
+func (receiver *variation) cacheKey(base string, client clientinfo.Info) string {
+	var builder strings.Builder
+	builder.WriteString(base)
+	for _, known := range receiver.conditions {
+		outcome := "?"
+		if matched, isKnown := known.expression.Evaluate(client); isKnown {
+			outcome = strconv.FormatBool(matched)
+		}
+		builder.WriteString("|" + strconv.Quote(known.raw) + "=" + outcome)
+	}
+	if receiver.device {
+		builder.WriteString("|device=" + client.Device)
+	}
+	return builder.String()
+}
+
+// conditionEvaluator evaluates the conditions of a page for the client, adding them to the variation of the page.
+type conditionEvaluator struct {
+	client clientinfo.Info
+	used   *variation
+}
+
+func (receiver conditionEvaluator) Evaluate(raw string) (bool, bool, error) {
+	parsed, err := condition.Parse(raw)
+	if err != nil {
+		return false, false, err
+	}
+	if receiver.used != nil {
+		receiver.used.addCondition(raw, parsed)
+	}
+	matched, known := parsed.Evaluate(receiver.client)
+	return matched, known, nil
+}
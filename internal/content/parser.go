@@ -1,14 +1,21 @@
 package content
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
+	"path"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"SfosBeginnerGuide/internal/cache"
 	"SfosBeginnerGuide/internal/markdown"
+	"SfosBeginnerGuide/internal/metrics"
+	"SfosBeginnerGuide/internal/tracing"
 
 	"github.com/yuin/goldmark"
 	"github.com/yuin/goldmark/parser"
@@ -17,34 +24,117 @@ import (
 )
 
 type Parser interface {
-	ParseByPath(path string) (*Item, error)
+	ParseByPath(ctx context.Context, path string, options Options) (*Item, error)
 }
 
 type MarkdownParser struct {
 	root     fs.FS
 	markdown goldmark.Markdown
+	qml      goldmark.Markdown
 	cache    cache.Store[*Item]
+	// variations holds the variation of every rendered page by path and format
+	variationsMu sync.Mutex
+	variations   map[string]*variation
+	// Redirects are used for pages which don't exist anymore
+	Redirects *Redirects
 }
 
-func NewMarkdownParser(root fs.FS, md goldmark.Markdown, cacheStore cache.Store[*Item]) *MarkdownParser {
+func NewMarkdownParser(root fs.FS, md goldmark.Markdown, qml goldmark.Markdown, cacheStore cache.Store[*Item]) *MarkdownParser {
 	return &MarkdownParser{
-		root:     root,
-		markdown: md,
-		cache:    cacheStore,
+		root:       root,
+		markdown:   md,
+		qml:        qml,
+		cache:      cacheStore,
+		variations: make(map[string]*variation),
 	}
 }
 
-func NewCachedMarkdownParser(root fs.FS, md goldmark.Markdown, ttl time.Duration) *MarkdownParser {
-	return NewMarkdownParser(root, md, cache.NewTTL[*Item](ttl))
+// NewCachedMarkdownParser keeps at most size rendered pages in memory.
+func NewCachedMarkdownParser(root fs.FS, md goldmark.Markdown, qml goldmark.Markdown, size int, ttl time.Duration) *MarkdownParser {
+	return NewMarkdownParser(root, md, qml, metrics.InstrumentCache("content", cache.NewLRU[*Item](size, ttl)))
 }
 
-func (receiver *MarkdownParser) ParseByPath(targetPath string) (*Item, error) {
+func (receiver *MarkdownParser) ParseByPath(ctx context.Context, targetPath string, options Options) (*Item, error) {
+	item, _, err := receiver.parseByPath(ctx, targetPath, options)
+	return item, err
+}
+
+// parseByPath returns the page together with its variation.
+func (receiver *MarkdownParser) parseByPath(ctx context.Context, targetPath string, options Options) (item *Item, used *variation, err error) {
+	ctx, span := tracing.Start(ctx, "content.ParseByPath")
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+
 	targetPath = markdown.NormalizePath(targetPath, "")
+	base := targetPath + "|" + string(options.format())
+	span.SetAttribute("content.path", targetPath)
+	span.SetAttribute("content.format", string(options.Format))
+
+	receiver.variationsMu.Lock()
+	known, ok := receiver.variations[base]
+	receiver.variationsMu.Unlock()
+	if ok {
+		if cached, ok := receiver.cache.Get(known.cacheKey(base, options.Client)); ok {
+			span.SetAttribute("cache.hit", true)
+			return cached, known, nil
+		}
+	}
+	span.SetAttribute("cache.hit", false)
 
-	if item, ok := receiver.cache.Get(targetPath); ok {
-		return item, nil
+	used = &variation{}
+	item, err = receiver.parseFile(ctx, targetPath, options, used)
+	if errors.Is(err, fs.ErrNotExist) {
+		if target, moved := receiver.Redirects.Resolve(targetPath); moved {
+			span.SetAttribute("content.redirect", target)
+			return nil, nil, &MovedError{Path: strings.TrimPrefix(target, "docs/")}
+		}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if _, _, isOverlay := ParseOverlayPath(targetPath); !isOverlay && receiver.hasOverlays(targetPath) {
+		// clients of other devices get the page with another overlay or none
+		used.device = true
+		if device := options.Client.Device; device != "" {
+			overlayPath := OverlayPath(targetPath, device)
+			if _, err := fs.Stat(receiver.root, overlayPath); err == nil {
+				overlay, err := receiver.parseFile(ctx, overlayPath, options, used)
+				if err != nil {
+					return nil, nil, err
+				}
+				item = mergeOverlay(item, overlay)
+				item.Overlay = strings.TrimPrefix(overlayPath, "docs/")
+			}
+		}
 	}
 
+	// the rendering is stored under everything known to change the page, which includes what it depends on
+	receiver.variationsMu.Lock()
+	used = receiver.variations[base].merge(used)
+	receiver.variations[base] = used
+	receiver.variationsMu.Unlock()
+	receiver.cache.Set(used.cacheKey(base, options.Client), item)
+
+	return item, used, nil
+}
+
+// hasOverlays tells whether any device has an overlay for the page.
+func (receiver *MarkdownParser) hasOverlays(pagePath string) bool {
+	directory, name := path.Split(pagePath)
+	entries, err := fs.ReadDir(receiver.root, path.Clean(directory))
+	if err != nil {
+		return false
+	}
+	prefix := strings.TrimSuffix(name, ".md") + overlayInfix
+	return slices.ContainsFunc(entries, func(entry fs.DirEntry) bool {
+		return !entry.IsDir() && strings.HasPrefix(entry.Name(), prefix) && strings.HasSuffix(entry.Name(), ".md")
+	})
+}
+
+func (receiver *MarkdownParser) parseFile(ctx context.Context, targetPath string, options Options, used *variation) (*Item, error) {
 	file, err := receiver.root.Open(targetPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open file %s: %w", targetPath, err)
@@ -56,72 +146,119 @@ func (receiver *MarkdownParser) ParseByPath(targetPath string) (*Item, error) {
 		return nil, fmt.Errorf("failed to read file %s: %w", targetPath, err)
 	}
 
-	item, err := receiver.parse(content, targetPath)
+	item, err := receiver.parse(ctx, content, targetPath, options, used)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse file %s: %w", targetPath, err)
 	}
 
-	receiver.cache.Set(targetPath, item)
-
 	return item, nil
 }
 
-func (receiver *MarkdownParser) parse(content []byte, currentFile string) (*Item, error) {
+func (receiver *MarkdownParser) parse(ctx context.Context, content []byte, currentFile string, options Options, used *variation) (*Item, error) {
 	result := &Item{Meta: &Meta{}}
 
-	ctx := parser.NewContext()
-	ctx.Set(markdown.LinkResolverContextKey, currentFile)
-	_ = receiver.markdown.Parser().Parse(text.NewReader(content), parser.WithContext(ctx))
+	parserContext := parser.NewContext()
+	parserContext.Set(markdown.LinkResolverContextKey, currentFile)
+	parserContext.Set(markdown.ConditionContextKey, conditionEvaluator{client: options.Client, used: used})
+	_ = receiver.markdown.Parser().Parse(text.NewReader(content), parser.WithContext(parserContext))
 
-	sectionsData, _ := ctx.Get(markdown.SectionContextKey).(*markdown.SectionInfo)
+	if conditionErrors, _ := parserContext.Get(markdown.ConditionErrorsContextKey).([]error); len(conditionErrors) > 0 {
+		return nil, errors.Join(conditionErrors...)
+	}
+
+	sectionsData, _ := parserContext.Get(markdown.SectionContextKey).(*markdown.SectionInfo)
 	if sectionsData == nil {
 		return nil, fmt.Errorf("section splitter did not run")
 	}
 
-	introHTML, err := markdown.RenderNodes(receiver.markdown, content, sectionsData.Intro)
-	if err != nil {
-		return nil, fmt.Errorf("failed to render intro content: %w", err)
-	}
-	result.Content = introHTML
+	if options.Format == FormatAST {
+		result.Blocks = markdown.BuildBlocks(content, sectionsData.Intro)
+		for _, section := range sectionsData.Sections {
+			result.Sections = append(result.Sections, &Section{
+				Title:  section.Title,
+				Blocks: markdown.BuildBlocks(content, section.Nodes),
+			})
+		}
+	} else {
+		renderer := receiver.markdown
+		if options.Format == FormatQML && receiver.qml != nil {
+			renderer = receiver.qml
+		}
 
-	for _, section := range sectionsData.Sections {
-		sectionHTML, err := markdown.RenderNodes(receiver.markdown, content, section.Nodes)
+		introHTML, err := markdown.RenderNodes(renderer, content, sectionsData.Intro)
 		if err != nil {
-			return nil, fmt.Errorf("failed to render section %s: %w", section.Title, err)
+			return nil, fmt.Errorf("failed to render intro content: %w", err)
 		}
+		result.Content = introHTML
 
-		result.Sections = append(result.Sections, &Section{
-			Title:   section.Title,
-			Content: sectionHTML,
-		})
+		for _, section := range sectionsData.Sections {
+			sectionHTML, err := markdown.RenderNodes(renderer, content, section.Nodes)
+			if err != nil {
+				return nil, fmt.Errorf("failed to render section %s: %w", section.Title, err)
+			}
+
+			result.Sections = append(result.Sections, &Section{
+				Title:   section.Title,
+				Content: sectionHTML,
+			})
+		}
 	}
 
-	if err := receiver.parseMetadata(ctx, result.Meta); err != nil {
+	if err := receiver.parseMetadata(parserContext, result.Meta); err != nil {
 		return nil, fmt.Errorf("failed parsing metadata: %w", err)
 	}
-	if err := receiver.parseLinks(result, currentFile); err != nil {
+	if result.Meta.Condition != "" {
+		matched, known, err := conditionEvaluator{client: options.Client, used: used}.Evaluate(result.Meta.Condition)
+		if err != nil {
+			return nil, fmt.Errorf("invalid page condition %q: %w", result.Meta.Condition, err)
+		}
+		result.NotApplicable = known && !matched
+	}
+	if len(result.Meta.Devices) > 0 {
+		used.device = true
+		if device := options.Client.Device; device != "" && !slices.Contains(result.Meta.Devices, device) {
+			result.NotApplicable = true
+		}
+	}
+
+	inlineActions, _ := parserContext.Get(markdown.ActionsContextKey).([]string)
+	result.Meta.Actions = mergeActions(result.Meta.Actions, inlineActions)
+	if err := receiver.parseLinks(ctx, result, currentFile, options, used); err != nil {
 		return nil, fmt.Errorf("failed parsing links: %w", err)
 	}
 
 	return result, nil
 }
 
-func (receiver *MarkdownParser) parseMetadata(ctx parser.Context, meta *Meta) error {
-	metadata := frontmatter.Get(ctx)
+func (receiver *MarkdownParser) parseMetadata(parserContext parser.Context, meta *Meta) error {
+	metadata := frontmatter.Get(parserContext)
+	if metadata == nil {
+		// overlays usually have no front matter of their own
+		return nil
+	}
 	return metadata.Decode(meta)
 }
 
-func (receiver *MarkdownParser) parseLinks(result *Item, currentFile string) error {
+func (receiver *MarkdownParser) parseLinks(ctx context.Context, result *Item, currentFile string, options Options, used *variation) error {
 	if len(result.Meta.Links) == 0 {
 		return nil
 	}
 
 	for _, rawLink := range result.Meta.Links {
 		targetFile := strings.TrimPrefix(markdown.NormalizePath(rawLink, currentFile), "docs/")
-		item, err := receiver.ParseByPath(targetFile)
+		item, linked, err := receiver.parseByPath(ctx, targetFile, options)
+		var moved *MovedError
+		if errors.As(err, &moved) {
+			targetFile = moved.Path
+			item, linked, err = receiver.parseByPath(ctx, targetFile, options)
+		}
 		if err != nil {
 			return fmt.Errorf("failed parsing link %s: %w", rawLink, err)
 		}
+		*used = *used.merge(linked)
+		if item.NotApplicable {
+			continue
+		}
 
 		result.Links = append(result.Links, &Link{
 			Link:  targetFile,
@@ -131,3 +268,21 @@ func (receiver *MarkdownParser) parseLinks(result *Item, currentFile string) err
 
 	return nil
 }
+
+func mergeActions(declared []string, inline []string) []string {
+	if len(inline) == 0 {
+		return declared
+	}
+
+	seen := make(map[string]struct{}, len(declared)+len(inline))
+	result := make([]string, 0, len(declared)+len(inline))
+	for _, action := range append(append([]string{}, declared...), inline...) {
+		if _, ok := seen[action]; ok {
+			continue
+		}
+		seen[action] = struct{}{}
+		result = append(result, action)
+	}
+
+	return result
+}
@@ -0,0 +1,129 @@
+package content
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"SfosBeginnerGuide/internal/cache"
+	"SfosBeginnerGuide/internal/clientinfo"
+	"SfosBeginnerGuide/internal/markdown"
+	"SfosBeginnerGuide/internal/version"
+)
+
+// countingStore counts the renderings stored in the cache.
+type countingStore struct {
+	cache.Store[*Item]
+	sets int
+}
+
+func (receiver *countingStore) Set(key string, value *Item) {
+	receiver.sets++
+	receiver.Store.Set(key, value)
+}
+
+func newTestParser(t *testing.T) (*MarkdownParser, *countingStore) {
+	t.Helper()
+	docs := fstest.MapFS{
+		"docs/en/plain.md":                     {Data: []byte("---\ntitle: Plain\n---\nThe same for everyone.\n")},
+		"docs/en/conditional.md":               {Data: []byte("---\ntitle: Conditional\n---\nIntro.\n\n:::if sfos>=5.0\nOnly on SailfishOS 5.\n:::\n")},
+		"docs/en/links.md":                     {Data: []byte("---\ntitle: Links\nlinks: [conditional.md]\n---\nSee also.\n")},
+		"docs/en/device.md":                    {Data: []byte("---\ntitle: Device\n---\nAny device.\n")},
+		"docs/en/device.device-xperia10iii.md": {Data: []byte("---\ntitle: Xperia\n---\nThe Xperia.\n")},
+	}
+	store := &countingStore{Store: cache.NewLRU[*Item](100, time.Minute)}
+	return NewMarkdownParser(docs, markdown.New(), markdown.New(), store), store
+}
+
+func sailfish(t *testing.T, raw string) clientinfo.Info {
+	t.Helper()
+	parsed, err := version.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return clientinfo.Info{SailfishVersion: parsed, AppVersion: parsed}
+}
+
+func TestParserCachesPagesWithoutConditionsOncePerFormat(t *testing.T) {
+	parser, store := newTestParser(t)
+
+	for _, client := range []clientinfo.Info{{}, sailfish(t, "4.6"), sailfish(t, "5.0.1"), {Architecture: "armv7hl"}} {
+		for _, format := range []Format{FormatHTML, FormatAST} {
+			if _, err := parser.ParseByPath(context.Background(), "en/plain.md", Options{Format: format, Client: client}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if store.sets != 2 {
+		t.Errorf("rendered %d times, expected once per format", store.sets)
+	}
+}
+
+func TestParserCachesPagesPerConditionOutcome(t *testing.T) {
+	parser, store := newTestParser(t)
+
+	for _, current := range []struct {
+		client   clientinfo.Info
+		included bool
+		renders  int
+	}{
+		{sailfish(t, "4.6"), false, 1},
+		{sailfish(t, "5.0"), true, 2},
+		{sailfish(t, "5.1"), true, 2},
+		{sailfish(t, "4.5"), false, 2},
+		// unknown clients get the conditional content wrapped, which is another rendering
+		{clientinfo.Info{}, true, 3},
+	} {
+		item, err := parser.ParseByPath(context.Background(), "en/conditional.md", Options{Client: current.client})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if included := strings.Contains(item.Content, "Only on SailfishOS 5."); included != current.included {
+			t.Errorf("%s: conditional content included %v", current.client.SailfishVersion, included)
+		}
+		if store.sets != current.renders {
+			t.Errorf("%s: rendered %d times, expected %d", current.client.SailfishVersion, store.sets, current.renders)
+		}
+	}
+}
+
+func TestParserCachesLinkingPagesPerLinkedConditions(t *testing.T) {
+	parser, store := newTestParser(t)
+
+	for _, raw := range []string{"4.6", "5.0", "4.5"} {
+		if _, err := parser.ParseByPath(context.Background(), "en/links.md", Options{Client: sailfish(t, raw)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// both pages are rendered once per outcome of the linked page's condition
+	if store.sets != 4 {
+		t.Errorf("rendered %d times, expected 4", store.sets)
+	}
+}
+
+func TestParserCachesOverlaidPagesPerDevice(t *testing.T) {
+	parser, store := newTestParser(t)
+
+	for _, current := range []struct {
+		device  string
+		overlay string
+	}{
+		{"", ""},
+		{"xperia10iii", "en/device.device-xperia10iii.md"},
+		{"", ""},
+		{"xperia10iii", "en/device.device-xperia10iii.md"},
+	} {
+		item, err := parser.ParseByPath(context.Background(), "en/device.md", Options{Client: clientinfo.Info{Device: current.device}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if item.Overlay != current.overlay {
+			t.Errorf("device %q: overlay %q, expected %q", current.device, item.Overlay, current.overlay)
+		}
+	}
+	if store.sets != 2 {
+		t.Errorf("rendered %d times, expected once per device", store.sets)
+	}
+}
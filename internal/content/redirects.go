@@ -0,0 +1,127 @@
+package content
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"SfosBeginnerGuide/internal/markdown"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxRedirectHops limits following redirects to pages which have moved again.
+const maxRedirectHops = 10
+
+// MovedError is returned for pages which don't exist anymore but have moved elsewhere.
+type MovedError struct {
+	// Path is the canonical path of the page, relative to the docs directory
+	Path string
+}
+
+func (receiver *MovedError) Error() string {
+	return "page moved to " + receiver.Path
+}
+
+type Redirect struct {
+	From string `yaml:"from"`
+	To   string `yaml:"to"`
+}
+
+// Redirects maps old paths of pages to their current ones, both as full paths including the docs directory.
+type Redirects struct {
+	targets map[string]string
+}
+
+// LoadRedirects reads the redirects file and the aliases in the front matter of every page. Paths in
+// the redirects file include the language, aliases are relative to the language of the page.
+func LoadRedirects(root fs.FS, dir string, redirectsPath string, parser Parser) (*Redirects, error) {
+	redirects := &Redirects{targets: make(map[string]string)}
+
+	data, err := fs.ReadFile(root, redirectsPath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read redirects %s: %w", redirectsPath, err)
+	}
+	var file struct {
+		Redirects []*Redirect `yaml:"redirects"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse redirects %s: %w", redirectsPath, err)
+	}
+	for _, redirect := range file.Redirects {
+		if redirect.From == "" || redirect.To == "" {
+			return nil, fmt.Errorf("redirects %s: redirect without from or to", redirectsPath)
+		}
+		if err := redirects.add(redirect.From, markdown.NormalizePath(redirect.To, "")); err != nil {
+			return nil, fmt.Errorf("redirects %s: %w", redirectsPath, err)
+		}
+	}
+
+	err = fs.WalkDir(root, dir, func(filePath string, entry fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if entry.IsDir() || path.Ext(filePath) != ".md" {
+			return nil
+		}
+		if _, _, isOverlay := ParseOverlayPath(filePath); isOverlay {
+			return nil
+		}
+
+		item, err := parser.ParseByPath(context.Background(), strings.TrimPrefix(filePath, dir+"/"), Options{Format: FormatHTML})
+		if err != nil {
+			// broken pages are reported by the validator and the readiness check
+			return nil
+		}
+		language, _, _ := strings.Cut(strings.TrimPrefix(filePath, dir+"/"), "/")
+		for _, alias := range item.Meta.Aliases {
+			if err := redirects.add(path.Join(language, strings.TrimPrefix(alias, "/")), filePath); err != nil {
+				return fmt.Errorf("aliases of %s: %w", filePath, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return redirects, nil
+}
+
+func (receiver *Redirects) add(from string, to string) error {
+	from = markdown.NormalizePath(from, "")
+	if existing, ok := receiver.targets[from]; ok {
+		return fmt.Errorf("%s redirects to both %s and %s", from, existing, to)
+	}
+	receiver.targets[from] = to
+	return nil
+}
+
+// Resolve returns the current path of a page which has moved, following further moves.
+func (receiver *Redirects) Resolve(targetPath string) (string, bool) {
+	if receiver == nil {
+		return "", false
+	}
+
+	current := markdown.NormalizePath(targetPath, "")
+	moved := false
+	for range maxRedirectHops {
+		next, ok := receiver.targets[current]
+		if !ok {
+			break
+		}
+		current, moved = next, true
+	}
+	return current, moved
+}
+
+// All returns the old paths and the paths they redirect to.
+func (receiver *Redirects) All() map[string]string {
+	if receiver == nil {
+		return nil
+	}
+	return receiver.targets
+}
@@ -0,0 +1,120 @@
+package content
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+type Problem struct {
+	Path    string
+	Message string
+}
+
+func (receiver Problem) String() string {
+	return receiver.Path + ": " + receiver.Message
+}
+
+// Validator checks every document in the docs directory for problems that would only surface
+// once the page is requested, like broken links, missing titles or unknown actions.
+type Validator struct {
+	root      fs.FS
+	dir       string
+	parser    Parser
+	actions   *ActionRegistry
+	devices   *DeviceRegistry
+	redirects *Redirects
+}
+
+func NewValidator(root fs.FS, dir string, parser Parser, actions *ActionRegistry, devices *DeviceRegistry, redirects *Redirects) *Validator {
+	return &Validator{
+		root:      root,
+		dir:       dir,
+		parser:    parser,
+		actions:   actions,
+		devices:   devices,
+		redirects: redirects,
+	}
+}
+
+func (receiver *Validator) Validate() ([]Problem, error) {
+	var problems []Problem
+
+	err := fs.WalkDir(receiver.root, receiver.dir, func(filePath string, entry fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if entry.IsDir() || path.Ext(filePath) != ".md" {
+			return nil
+		}
+
+		problems = append(problems, receiver.validateFile(filePath)...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed walking %s: %w", receiver.dir, err)
+	}
+
+	return append(problems, receiver.validateRedirects()...), nil
+}
+
+func (receiver *Validator) validateRedirects() []Problem {
+	var problems []Problem
+	for _, from := range slices.Sorted(maps.Keys(receiver.redirects.All())) {
+		if _, err := fs.Stat(receiver.root, from); err == nil {
+			problems = append(problems, Problem{Path: from, Message: "redirected page still exists, the redirect is never used"})
+		}
+		target, _ := receiver.redirects.Resolve(from)
+		if _, err := fs.Stat(receiver.root, target); err != nil {
+			problems = append(problems, Problem{Path: from, Message: fmt.Sprintf("redirect to missing page %s", target)})
+		}
+	}
+	return problems
+}
+
+func (receiver *Validator) validateFile(filePath string) []Problem {
+	item, err := receiver.parser.ParseByPath(context.Background(), strings.TrimPrefix(filePath, receiver.dir+"/"), Options{Format: FormatHTML})
+	if err != nil {
+		return []Problem{{Path: filePath, Message: err.Error()}}
+	}
+
+	var problems []Problem
+	basePath, overlayDevice, isOverlay := ParseOverlayPath(filePath)
+	if isOverlay {
+		if _, ok := receiver.devices.Get(overlayDevice); !ok {
+			problems = append(problems, Problem{Path: filePath, Message: fmt.Sprintf("overlay for unknown device %s", overlayDevice)})
+		}
+		if _, err := fs.Stat(receiver.root, basePath); err != nil {
+			problems = append(problems, Problem{Path: filePath, Message: fmt.Sprintf("overlay without base page %s", basePath)})
+		}
+	} else if strings.TrimSpace(item.Meta.Title) == "" {
+		problems = append(problems, Problem{Path: filePath, Message: "missing title"})
+	}
+	if item.Meta.LastReviewed != "" {
+		if _, err := time.Parse(LastReviewedLayout, item.Meta.LastReviewed); err != nil {
+			problems = append(problems, Problem{Path: filePath, Message: fmt.Sprintf("invalid lastReviewed %q, expected YYYY-MM-DD", item.Meta.LastReviewed)})
+		}
+	}
+	switch item.Meta.Difficulty {
+	case "", DifficultyBeginner, DifficultyIntermediate, DifficultyAdvanced:
+	default:
+		problems = append(problems, Problem{Path: filePath, Message: fmt.Sprintf("invalid difficulty %q, expected beginner, intermediate or advanced", item.Meta.Difficulty)})
+	}
+	for _, device := range item.Meta.Devices {
+		if _, ok := receiver.devices.Get(device); !ok {
+			problems = append(problems, Problem{Path: filePath, Message: fmt.Sprintf("unknown device %s", device)})
+		}
+	}
+	for _, action := range item.Meta.Actions {
+		if err := receiver.actions.Validate(action); err != nil {
+			problems = append(problems, Problem{Path: filePath, Message: err.Error()})
+		}
+	}
+
+	return problems
+}
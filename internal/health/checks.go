@@ -0,0 +1,65 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"SfosBeginnerGuide/internal/content"
+	"SfosBeginnerGuide/internal/search"
+)
+
+// DocsCheck parses every document, the parser caches the results so repeated checks are cheap.
+func DocsCheck(root fs.FS, dir string, parser content.Parser) CheckFunc {
+	return func(ctx context.Context) error {
+		var errs []error
+		err := fs.WalkDir(root, dir, func(filePath string, entry fs.DirEntry, walkErr error) error {
+			if walkErr != nil {
+				return walkErr
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if entry.IsDir() || path.Ext(filePath) != ".md" {
+				return nil
+			}
+
+			if _, err := parser.ParseByPath(ctx, strings.TrimPrefix(filePath, dir+"/"), content.Options{Format: content.FormatHTML}); err != nil {
+				errs = append(errs, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed walking %s: %w", dir, err)
+		}
+
+		if len(errs) > 0 {
+			return fmt.Errorf("%d document(s) failed to parse: %w", len(errs), errors.Join(errs...))
+		}
+		return nil
+	}
+}
+
+func SearchIndexCheck(searcher *search.Service, languages content.LanguageProvider) CheckFunc {
+	return func(ctx context.Context) error {
+		list, err := languages.List()
+		if err != nil {
+			return err
+		}
+
+		var errs []error
+		for _, language := range list {
+			if err := searcher.CheckIndex(ctx, language); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	}
+}
+
+func EmbeddingsCheck(searcher *search.Service) CheckFunc {
+	return searcher.CheckEmbeddings
+}
@@ -0,0 +1,92 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	StatusOK       = "ok"
+	StatusDegraded = "degraded"
+	StatusFailing  = "failing"
+)
+
+type CheckFunc func(ctx context.Context) error
+
+type Report struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks"`
+}
+
+type CheckResult struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Critical bool   `json:"critical"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+type check struct {
+	name     string
+	critical bool
+	run      CheckFunc
+}
+
+// Checker runs the readiness checks concurrently. A failing critical check makes the whole report failing,
+// a failing non-critical check only marks it as degraded.
+type Checker struct {
+	checks []check
+}
+
+func NewChecker() *Checker {
+	return &Checker{}
+}
+
+func (receiver *Checker) Add(name string, critical bool, run CheckFunc) {
+	receiver.checks = append(receiver.checks, check{name: name, critical: critical, run: run})
+}
+
+func (receiver *Checker) Run(ctx context.Context) *Report {
+	results := make([]CheckResult, len(receiver.checks))
+
+	var wg sync.WaitGroup
+	for i, current := range receiver.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			start := time.Now()
+			err := current.run(ctx)
+			results[i] = CheckResult{
+				Name:     current.name,
+				Status:   StatusOK,
+				Critical: current.critical,
+				Duration: time.Since(start).Round(time.Millisecond).String(),
+			}
+			if err != nil {
+				results[i].Status = StatusFailing
+				results[i].Error = err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+
+	report := &Report{Status: StatusOK, Checks: results}
+	for _, result := range results {
+		if result.Status == StatusOK {
+			continue
+		}
+		if result.Critical {
+			report.Status = StatusFailing
+			break
+		}
+		report.Status = StatusDegraded
+	}
+
+	return report
+}
+
+func (receiver *Report) Ready() bool {
+	return receiver.Status != StatusFailing
+}
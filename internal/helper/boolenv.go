@@ -1,19 +0,0 @@
-package helper
-
-import (
-	"os"
-	"strconv"
-	"strings"
-)
-
-func BoolEnv(name string, fallback bool) bool {
-	value, ok := os.LookupEnv(name)
-	if !ok || strings.TrimSpace(value) == "" {
-		return fallback
-	}
-	parsed, err := strconv.ParseBool(value)
-	if err != nil {
-		return fallback
-	}
-	return parsed
-}
@@ -0,0 +1,13 @@
+package helper
+
+import "runtime/debug"
+
+// BuildVersion returns the version of the main module as recorded by the go toolchain,
+// "(devel)" for local builds.
+func BuildVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok || info.Main.Version == "" {
+		return "(devel)"
+	}
+	return info.Main.Version
+}
@@ -2,19 +2,22 @@ package httpapi
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
-	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
-	"strings"
+	"strconv"
 	"time"
 
+	"SfosBeginnerGuide/internal/assets"
+	"SfosBeginnerGuide/internal/clientinfo"
+	"SfosBeginnerGuide/internal/config"
 	"SfosBeginnerGuide/internal/content"
+	"SfosBeginnerGuide/internal/health"
 	"SfosBeginnerGuide/internal/helper"
 	"SfosBeginnerGuide/internal/httpx"
 	"SfosBeginnerGuide/internal/search"
+	"SfosBeginnerGuide/internal/version"
 )
 
 type ErrorResponse struct {
@@ -25,34 +28,162 @@ func NewErrorResponse(message string) *ErrorResponse {
 	return &ErrorResponse{Error: message}
 }
 
+// MovedResponse keeps the error field so that apps which don't follow redirects still show an error.
+type MovedResponse struct {
+	Error    string `json:"error"`
+	Path     string `json:"path"`
+	Location string `json:"location"`
+}
+
+// SearchEventResponse is an event of a streamed search for the apps released before the versioned API.
+type SearchEventResponse struct {
+	Stage   search.Stage    `json:"stage"`
+	Results []search.Result `json:"results"`
+}
+
 type Handler struct {
 	Parser    content.Parser
 	Languages content.LanguageProvider
 	Searcher  SearchService
+	Assets    AssetProvider
+	Actions   ActionProvider
+	Devices   DeviceProvider
+	Config    *config.Config
+	Health    ReadinessChecker
+	Presenter Presenter
+}
+
+// Presenter shapes the response bodies for one version of the API.
+type Presenter interface {
+	Page(item *content.Item) any
+	Moved(path string, location string) any
+	SearchResults(response *search.Response) any
+	SearchEvent(stage search.Stage, response *search.Response) any
+	Languages(languages []string) any
+	Devices(devices []*content.Device) any
 }
 
 type SearchService interface {
-	Search(ctx context.Context, language, query string, limit int) ([]search.Result, error)
+	Search(ctx context.Context, request search.Request) (*search.Response, error)
+	SearchStream(ctx context.Context, request search.Request, emit search.EmitFunc) error
+	Status(ctx context.Context) search.Status
 }
 
-func NewHandler(parser content.Parser, languages content.LanguageProvider, searcher SearchService) *Handler {
-	return &Handler{Parser: parser, Languages: languages, Searcher: searcher}
+type AssetProvider interface {
+	Variant(imagePath string, width int) (*assets.Variant, error)
+}
+
+type ActionProvider interface {
+	Filter(item *content.Item, appVersion version.Version) *content.Item
+	SupportedActions(appVersion version.Version) []string
+}
+
+type DeviceProvider interface {
+	List() []*content.Device
+	Get(id string) (*content.Device, bool)
+}
+
+type ReadinessChecker interface {
+	Run(ctx context.Context) *health.Report
+}
+
+type CapabilitiesResponse struct {
+	// Searching is kept for older app versions, it's true only when searching actually works
+	Searching     bool                                `json:"searching"`
+	Search        search.Status                       `json:"search"`
+	OfflineBundle *LinkCapability                     `json:"offlineBundle"`
+	Feedback      *LinkCapability                     `json:"feedback"`
+	Languages     []string                            `json:"languages"`
+	Actions       []string                            `json:"actions"`
+	Client        clientinfo.Info                     `json:"client"`
+	Server        ServerInfo                          `json:"server"`
+	Dependencies  map[string]*search.DependencyStatus `json:"dependencies"`
+}
+
+type LinkCapability struct {
+	Enabled bool   `json:"enabled"`
+	URL     string `json:"url,omitempty"`
+}
+
+type ServerInfo struct {
+	Version string `json:"version"`
+}
+
+func newLinkCapability(url string) *LinkCapability {
+	return &LinkCapability{Enabled: url != "", URL: url}
+}
+
+func NewHandler(
+	parser content.Parser,
+	languages content.LanguageProvider,
+	searcher SearchService,
+	assetProvider AssetProvider,
+	actions ActionProvider,
+	devices DeviceProvider,
+	cfg *config.Config,
+	readiness ReadinessChecker,
+) *Handler {
+	return &Handler{
+		Parser:    parser,
+		Languages: languages,
+		Searcher:  searcher,
+		Assets:    assetProvider,
+		Actions:   actions,
+		Devices:   devices,
+		Config:    cfg,
+		Health:    readiness,
+		Presenter: legacyPresenter{},
+	}
+}
+
+// withPresenter returns a copy of the handler responding with the bodies of another API version.
+func (receiver *Handler) withPresenter(presenter Presenter) *Handler {
+	handler := *receiver
+	handler.Presenter = presenter
+	return &handler
+}
+
+// legacyPresenter returns the internal models as they are, which is what the apps released before
+// the versioned API expect.
+type legacyPresenter struct{}
+
+func (legacyPresenter) Page(item *content.Item) any                 { return item }
+func (legacyPresenter) SearchResults(response *search.Response) any { return response.Results }
+func (legacyPresenter) Languages(languages []string) any            { return languages }
+func (legacyPresenter) Devices(devices []*content.Device) any       { return devices }
+
+func (legacyPresenter) SearchEvent(stage search.Stage, response *search.Response) any {
+	return &SearchEventResponse{Stage: stage, Results: response.Results}
+}
+
+func (legacyPresenter) Moved(path string, location string) any {
+	return &MovedResponse{Error: "The page has moved", Path: path, Location: location}
 }
 
 func (receiver *Handler) Content(writer http.ResponseWriter, request *http.Request) {
 	defer httpx.DrainBody(request)
 
-	if request.Method != http.MethodGet {
+	options, err := contentOptions(request, receiver.Devices)
+	if err != nil {
 		httpx.WriteJSON(
-			http.StatusMethodNotAllowed,
-			NewErrorResponse("Method not allowed"),
+			http.StatusBadRequest,
+			NewErrorResponse("Invalid content options: "+err.Error()),
 			writer,
 		)
 		return
 	}
 
-	path := request.URL.Path
-	file, err := receiver.Parser.ParseByPath(path)
+	writer.Header().Add("Vary", "Accept, "+clientinfo.VaryHeaders)
+
+	path := request.PathValue("lang") + "/" + request.PathValue("path")
+	file, err := receiver.Parser.ParseByPath(request.Context(), path, options)
+	var moved *content.MovedError
+	if errors.As(err, &moved) {
+		location := contentURL(request, moved.Path)
+		writer.Header().Set("Location", location)
+		httpx.WriteJSON(http.StatusPermanentRedirect, receiver.Presenter.Moved(moved.Path, location), writer)
+		return
+	}
 	if errors.Is(err, os.ErrNotExist) {
 		httpx.WriteJSON(
 			http.StatusNotFound,
@@ -62,7 +193,7 @@ func (receiver *Handler) Content(writer http.ResponseWriter, request *http.Reque
 		return
 	}
 	if err != nil {
-		log.Println(err)
+		slog.ErrorContext(request.Context(), "failed parsing content", "error", err)
 		httpx.WriteJSON(
 			http.StatusInternalServerError,
 			NewErrorResponse("Failed parsing"),
@@ -71,151 +202,255 @@ func (receiver *Handler) Content(writer http.ResponseWriter, request *http.Reque
 		return
 	}
 
-	httpx.WriteOK(file, writer)
+	file = receiver.Actions.Filter(file, clientinfo.FromContext(request.Context()).AppVersion)
+
+	httpx.WriteOK(receiver.Presenter.Page(file), writer)
 }
 
-func (receiver *Handler) LanguagesList(writer http.ResponseWriter, _ *http.Request) {
+func (receiver *Handler) Asset(writer http.ResponseWriter, request *http.Request) {
+	defer httpx.DrainBody(request)
+
+	width := 0
+	if rawWidth := request.URL.Query().Get("w"); rawWidth != "" {
+		parsed, err := strconv.Atoi(rawWidth)
+		if err != nil || parsed < 0 {
+			httpx.WriteJSON(
+				http.StatusBadRequest,
+				NewErrorResponse("Invalid query parameter: w"),
+				writer,
+			)
+			return
+		}
+		width = parsed
+	}
+
+	imagePath := "docs/" + request.PathValue("path")
+	variant, err := receiver.Assets.Variant(imagePath, width)
+	if errors.Is(err, os.ErrNotExist) {
+		httpx.WriteJSON(
+			http.StatusNotFound,
+			NewErrorResponse("No asset could be found at the requested URL"),
+			writer,
+		)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(request.Context(), "failed loading asset", "error", err)
+		httpx.WriteJSON(
+			http.StatusInternalServerError,
+			NewErrorResponse("Failed loading asset"),
+			writer,
+		)
+		return
+	}
+
+	writer.Header().Set("Content-Type", variant.ContentType)
+	writer.Header().Set("Content-Length", strconv.Itoa(len(variant.Data)))
+	writer.Header().Set("Cache-Control", "public, max-age=86400")
+	writer.WriteHeader(http.StatusOK)
+	_, _ = writer.Write(variant.Data)
+}
+
+func (receiver *Handler) LanguagesList(writer http.ResponseWriter, request *http.Request) {
 	languages, err := receiver.Languages.List()
 	if err != nil {
-		log.Println(err)
+		slog.ErrorContext(request.Context(), "failed listing languages", "error", err)
 
 		httpx.WriteJSON(http.StatusInternalServerError, NewErrorResponse("Internal error"), writer)
 		return
 	}
 
-	httpx.WriteOK(languages, writer)
+	httpx.WriteOK(receiver.Presenter.Languages(languages), writer)
+}
+
+func (receiver *Handler) DevicesList(writer http.ResponseWriter, request *http.Request) {
+	defer httpx.DrainBody(request)
+
+	httpx.WriteOK(receiver.Presenter.Devices(receiver.Devices.List()), writer)
 }
 
 func (receiver *Handler) Capabilities(writer http.ResponseWriter, request *http.Request) {
 	defer httpx.DrainBody(request)
 
-	if request.Method != http.MethodGet {
-		httpx.WriteJSON(
-			http.StatusMethodNotAllowed,
-			NewErrorResponse("Method not allowed"),
-			writer,
-		)
+	languages, err := receiver.Languages.List()
+	if err != nil {
+		slog.ErrorContext(request.Context(), "failed listing languages", "error", err)
+
+		httpx.WriteJSON(http.StatusInternalServerError, NewErrorResponse("Internal error"), writer)
 		return
 	}
 
-	capabilities := map[string]bool{
-		"searching": helper.BoolEnv("CAPABILITY_SEARCHING", false),
+	client := clientinfo.FromContext(request.Context())
+	searchStatus := receiver.Searcher.Status(request.Context())
+
+	dependencies := map[string]*search.DependencyStatus{}
+	if searchStatus.Embeddings != nil {
+		dependencies["embeddings"] = searchStatus.Embeddings
+	}
+
+	capabilities := &CapabilitiesResponse{
+		Searching:     searchStatus.Available,
+		Search:        searchStatus,
+		OfflineBundle: newLinkCapability(receiver.Config.OfflineBundleURL),
+		Feedback:      newLinkCapability(receiver.Config.FeedbackURL),
+		Languages:     languages,
+		Actions:       receiver.Actions.SupportedActions(client.AppVersion),
+		Client:        client,
+		Server:        ServerInfo{Version: helper.BuildVersion()},
+		Dependencies:  dependencies,
 	}
 
+	writer.Header().Add("Vary", clientinfo.VaryHeaders)
 	httpx.WriteOK(capabilities, writer)
 }
 
+func (receiver *Handler) Liveness(writer http.ResponseWriter, request *http.Request) {
+	defer httpx.DrainBody(request)
+
+	writer.Header().Set("Cache-Control", "no-store")
+	httpx.WriteOK(&health.Report{Status: health.StatusOK, Checks: []health.CheckResult{}}, writer)
+}
+
+func (receiver *Handler) Readiness(writer http.ResponseWriter, request *http.Request) {
+	defer httpx.DrainBody(request)
+
+	ctx, cancel := context.WithTimeout(request.Context(), 10*time.Second)
+	defer cancel()
+
+	report := receiver.Health.Run(ctx)
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+
+	writer.Header().Set("Cache-Control", "no-store")
+	httpx.WriteJSON(status, report, writer)
+}
+
 func (receiver *Handler) Search(writer http.ResponseWriter, request *http.Request) {
 	defer httpx.DrainBody(request)
 
-	if request.Method != "QUERY" {
+	// only GET responses are cacheable, errors and the bodies of the other methods never are
+	writer.Header().Set("Cache-Control", "no-store")
+
+	lang := request.PathValue("lang")
+	body, problem := parseSearchRequest(request)
+	if problem != "" {
 		httpx.WriteJSON(
-			http.StatusMethodNotAllowed,
-			NewErrorResponse("Method not allowed (expected QUERY)"),
+			http.StatusBadRequest,
+			NewErrorResponse(problem),
 			writer,
 		)
 		return
 	}
 
-	type searchRequest struct {
-		Query string `json:"query"`
-		Top   *int   `json:"top"`
+	limit := receiver.Config.Search.DefaultResults
+	if body.Top != nil && *body.Top > 0 {
+		limit = min(*body.Top, receiver.Config.Search.MaxResults)
+	}
+
+	ctx, cancel := context.WithTimeout(request.Context(), receiver.Config.Search.Timeout.Std())
+	defer cancel()
+
+	searchRequest := search.Request{
+		Language: lang,
+		Query:    body.Query,
+		Limit:    limit,
+		Filters:  body.Filters,
+		Sort:     body.Sort,
+		Cursor:   body.Cursor,
+	}
+
+	if mediaType := streamMediaType(request); mediaType != "" {
+		receiver.searchStream(ctx, writer, request, searchRequest, mediaType)
+		return
+	}
+
+	response, err := receiver.Searcher.Search(ctx, searchRequest)
+	if err != nil {
+		writeSearchError(writer, request, err)
+		return
+	}
+
+	if request.Method == http.MethodGet {
+		// shared caches would answer popular searches without them being recorded
+		visibility := "public"
+		if receiver.Config.Search.AnalyticsFile != "" {
+			visibility = "private"
+		}
+		writer.Header().Set("Cache-Control", visibility+", max-age="+strconv.Itoa(int(receiver.Config.Search.CacheMaxAge.Std().Seconds())))
+		writer.Header().Add("Vary", "Accept, Accept-Encoding")
+	}
+	httpx.WriteOK(receiver.Presenter.SearchResults(response), writer)
+}
+
+// searchStream sends the fast results as soon as they are known and the refined ones once reranked.
+func (receiver *Handler) searchStream(ctx context.Context, writer http.ResponseWriter, request *http.Request, searchRequest search.Request, mediaType string) {
+	stream := newEventStream(writer, mediaType)
+	err := receiver.Searcher.SearchStream(ctx, searchRequest, func(stage search.Stage, response *search.Response) error {
+		return stream.Send(string(stage), receiver.Presenter.SearchEvent(stage, response))
+	})
+	if err == nil {
+		return
 	}
+	if !stream.started {
+		writeSearchError(writer, request, err)
+		return
+	}
+	if errors.Is(err, context.Canceled) {
+		// the client went away
+		return
+	}
+
+	slog.ErrorContext(request.Context(), "streamed search failed", "error", err)
+	_ = stream.Send("error", NewErrorResponse("Failed to search embeddings"))
+}
 
-	lang := strings.TrimPrefix(request.URL.Path, "/search")
-	lang = strings.TrimPrefix(lang, "/")
-	lang, _, _ = strings.Cut(lang, "/")
-	if lang == "" {
+func writeSearchError(writer http.ResponseWriter, request *http.Request, err error) {
+	if errors.Is(err, search.ErrSearchDisabled) {
 		httpx.WriteJSON(
-			http.StatusBadRequest,
-			NewErrorResponse("Missing language in path (expected /search/{lang})"),
+			http.StatusForbidden,
+			NewErrorResponse("Search capability is disabled"),
 			writer,
 		)
 		return
 	}
-
-	var body searchRequest
-	if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
-		if errors.Is(err, io.EOF) {
-			httpx.WriteJSON(
-				http.StatusBadRequest,
-				NewErrorResponse("Missing JSON body"),
-				writer,
-			)
-			return
-		}
+	if errors.Is(err, search.ErrEmbeddingsServerMissing) {
 		httpx.WriteJSON(
-			http.StatusBadRequest,
-			NewErrorResponse("Invalid JSON body"),
+			http.StatusInternalServerError,
+			NewErrorResponse("Search capability is enabled but EMBEDDINGS_SERVER is not configured"),
 			writer,
 		)
 		return
 	}
-
-	query := strings.TrimSpace(body.Query)
-	if query == "" {
+	if errors.Is(err, search.ErrInvalidCursor) {
 		httpx.WriteJSON(
 			http.StatusBadRequest,
-			NewErrorResponse("Missing body field: query"),
+			NewErrorResponse("Invalid cursor"),
 			writer,
 		)
 		return
 	}
-
-	limit := 20
-	if body.Top != nil && *body.Top > 0 {
-		parsed := *body.Top
-		if parsed > 100 {
-			parsed = 100
-		}
-		limit = parsed
+	if errors.Is(err, search.ErrLanguageNotFound) {
+		httpx.WriteJSON(
+			http.StatusNotFound,
+			NewErrorResponse("Unknown language"),
+			writer,
+		)
+		return
 	}
-
-	ctx, cancel := context.WithTimeout(request.Context(), 2*time.Minute)
-	defer cancel()
-
-	results, err := receiver.Searcher.Search(ctx, lang, query, limit)
-	if err != nil {
-		if errors.Is(err, search.ErrSearchDisabled) {
-			httpx.WriteJSON(
-				http.StatusForbidden,
-				NewErrorResponse("Search capability is disabled"),
-				writer,
-			)
-			return
-		}
-		if errors.Is(err, search.ErrEmbeddingsServerMissing) {
-			httpx.WriteJSON(
-				http.StatusInternalServerError,
-				NewErrorResponse("Search capability is enabled but EMBEDDINGS_SERVER is not configured"),
-				writer,
-			)
-			return
-		}
-		if errors.Is(err, search.ErrLanguageNotFound) {
-			httpx.WriteJSON(
-				http.StatusNotFound,
-				NewErrorResponse("Unknown language"),
-				writer,
-			)
-			return
-		}
-		if errors.Is(err, search.ErrAssetsUnavailable) {
-			httpx.WriteJSON(
-				http.StatusInternalServerError,
-				NewErrorResponse("Search assets are not configured on the server"),
-				writer,
-			)
-			return
-		}
-		log.Println(err)
+	if errors.Is(err, search.ErrAssetsUnavailable) {
 		httpx.WriteJSON(
 			http.StatusInternalServerError,
-			NewErrorResponse("Failed to search embeddings"),
+			NewErrorResponse("Search assets are not configured on the server"),
 			writer,
 		)
 		return
 	}
-
-	httpx.WriteOK(results, writer)
+	slog.ErrorContext(request.Context(), "search failed", "error", err)
+	httpx.WriteJSON(
+		http.StatusInternalServerError,
+		NewErrorResponse("Failed to search embeddings"),
+		writer,
+	)
 }
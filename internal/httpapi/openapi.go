@@ -0,0 +1,42 @@
+package httpapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+//go:embed openapi.json
+var openAPISpec []byte
+
+func openAPI(writer http.ResponseWriter, _ *http.Request) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.Header().Set("Content-Length", strconv.Itoa(len(openAPISpec)))
+	writer.WriteHeader(http.StatusOK)
+	_, _ = writer.Write(openAPISpec)
+}
+
+// CheckOpenAPI verifies that the published spec is valid JSON and describes every versioned route,
+// so that the spec can't silently fall behind the router.
+func CheckOpenAPI() error {
+	var spec struct {
+		OpenAPI string                                `json:"openapi"`
+		Paths   map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(openAPISpec, &spec); err != nil {
+		return fmt.Errorf("invalid openapi.json: %w", err)
+	}
+
+	var errs []error
+	for _, current := range apiRoutes {
+		path := APIPrefix + strings.ReplaceAll(current.path, "...}", "}")
+		if _, ok := spec.Paths[path][strings.ToLower(current.method)]; !ok {
+			errs = append(errs, fmt.Errorf("openapi.json does not describe %s %s", current.method, path))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,473 @@
+package httpapi
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"SfosBeginnerGuide/internal/assets"
+	"SfosBeginnerGuide/internal/clientinfo"
+	"SfosBeginnerGuide/internal/config"
+	"SfosBeginnerGuide/internal/content"
+	"SfosBeginnerGuide/internal/health"
+	"SfosBeginnerGuide/internal/markdown"
+	"SfosBeginnerGuide/internal/search"
+
+	"github.com/yuin/goldmark"
+)
+
+// contractCase is a request whose response has to match the response schema of its operation in openapi.json.
+type contractCase struct {
+	name   string
+	method string
+	path   string
+	accept string
+	device string
+	body   string
+	status int
+	// route is the pattern of apiRoutes the request is served by, used to check that every route is covered
+	route string
+}
+
+func TestOpenAPIContract(t *testing.T) {
+	spec := loadSpec(t)
+	server := httptest.NewServer(newContractHandler(t, true))
+	t.Cleanup(server.Close)
+
+	cases := []contractCase{
+		{name: "languages", method: http.MethodGet, path: "/languages", status: http.StatusOK, route: "GET /languages"},
+		{name: "capabilities", method: http.MethodGet, path: "/capabilities", status: http.StatusOK, route: "GET /capabilities"},
+		{name: "devices", method: http.MethodGet, path: "/devices", status: http.StatusOK, route: "GET /devices"},
+
+		{name: "content html", method: http.MethodGet, path: "/content/en/index.md", status: http.StatusOK, route: "GET /content/{lang}/{path...}"},
+		{name: "content qml", method: http.MethodGet, path: "/content/en/apps/store.md?format=qml", status: http.StatusOK, route: "GET /content/{lang}/{path...}"},
+		{name: "content ast", method: http.MethodGet, path: "/content/en/index.md?format=ast", status: http.StatusOK, route: "GET /content/{lang}/{path...}"},
+		{name: "content device overlay", method: http.MethodGet, path: "/content/en/apps/store.md?format=ast", device: "xperia10iii", status: http.StatusOK, route: "GET /content/{lang}/{path...}"},
+		{name: "content moved", method: http.MethodGet, path: "/content/en/apps/old-store.md", status: http.StatusPermanentRedirect, route: "GET /content/{lang}/{path...}"},
+		{name: "content missing", method: http.MethodGet, path: "/content/en/missing.md", status: http.StatusNotFound, route: "GET /content/{lang}/{path...}"},
+		{name: "content invalid format", method: http.MethodGet, path: "/content/en/index.md?format=pdf", status: http.StatusBadRequest, route: "GET /content/{lang}/{path...}"},
+
+		{name: "search get", method: http.MethodGet, path: "/search/en?q=store&top=1", status: http.StatusOK, route: "GET /search/{lang}"},
+		{name: "search get sorted", method: http.MethodGet, path: "/search/en?q=store+welcome&sort=title&tag=apps", status: http.StatusOK, route: "GET /search/{lang}"},
+		{name: "search post", method: http.MethodPost, path: "/search/en", body: `{"query": "store", "filters": {"pathPrefix": "apps/"}}`, status: http.StatusOK, route: "POST /search/{lang}"},
+		{name: "search query", method: "QUERY", path: "/search/en", body: `{"query": "welcom"}`, status: http.StatusOK, route: "QUERY /search/{lang}"},
+		{name: "search ndjson", method: http.MethodGet, path: "/search/en?q=store", accept: ndjsonMediaType, status: http.StatusOK, route: "GET /search/{lang}"},
+		{name: "search sse", method: http.MethodPost, path: "/search/en", body: `{"query": "store"}`, accept: sseMediaType, status: http.StatusOK, route: "POST /search/{lang}"},
+		{name: "search without query", method: http.MethodGet, path: "/search/en", status: http.StatusBadRequest, route: "GET /search/{lang}"},
+		{name: "search invalid cursor", method: http.MethodGet, path: "/search/en?q=store&cursor=nope", status: http.StatusBadRequest, route: "GET /search/{lang}"},
+		{name: "search unknown language", method: http.MethodPost, path: "/search/xx", body: `{"query": "store"}`, status: http.StatusNotFound, route: "POST /search/{lang}"},
+
+		{name: "asset", method: http.MethodGet, path: "/assets/en/apps/store.png", status: http.StatusOK, route: "GET /assets/{path...}"},
+		{name: "asset variant", method: http.MethodGet, path: "/assets/en/apps/store.png?w=2", status: http.StatusOK, route: "GET /assets/{path...}"},
+		{name: "asset invalid width", method: http.MethodGet, path: "/assets/en/apps/store.png?w=abc", status: http.StatusBadRequest, route: "GET /assets/{path...}"},
+		{name: "asset missing", method: http.MethodGet, path: "/assets/en/missing.png", status: http.StatusNotFound, route: "GET /assets/{path...}"},
+	}
+
+	covered := make(map[string]bool)
+	for _, current := range cases {
+		t.Run(current.name, func(t *testing.T) {
+			covered[current.route] = true
+			checkContract(t, spec, server.URL, current)
+		})
+	}
+
+	for _, current := range apiRoutes {
+		if pattern := current.method + " " + current.path; !covered[pattern] {
+			t.Errorf("route %s is not covered by the contract test", pattern)
+		}
+	}
+}
+
+func TestOpenAPIContractSearchDisabled(t *testing.T) {
+	spec := loadSpec(t)
+	server := httptest.NewServer(newContractHandler(t, false))
+	t.Cleanup(server.Close)
+
+	checkContract(t, spec, server.URL, contractCase{
+		method: http.MethodGet,
+		path:   "/search/en?q=store",
+		status: http.StatusForbidden,
+	})
+}
+
+func TestOpenAPIContractMuxErrors(t *testing.T) {
+	server := httptest.NewServer(newContractHandler(t, true))
+	t.Cleanup(server.Close)
+	spec := loadSpec(t)
+	errorSchema := map[string]any{"$ref": "#/components/schemas/Error"}
+
+	for _, current := range []struct {
+		method string
+		path   string
+		status int
+	}{
+		{http.MethodDelete, APIPrefix + "/languages", http.StatusMethodNotAllowed},
+		{http.MethodGet, APIPrefix + "/unknown", http.StatusNotFound},
+	} {
+		request, err := http.NewRequest(current.method, server.URL+current.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(response.Body)
+		response.Body.Close()
+
+		if response.StatusCode != current.status {
+			t.Errorf("%s %s: status %d, expected %d", current.method, current.path, response.StatusCode, current.status)
+		}
+		for _, err := range spec.validate(errorSchema, decodeJSON(t, body), "$", true) {
+			t.Errorf("%s %s: %v", current.method, current.path, err)
+		}
+	}
+}
+
+func checkContract(t *testing.T, spec *openAPIDocument, baseURL string, current contractCase) {
+	t.Helper()
+
+	request, err := http.NewRequest(current.method, baseURL+APIPrefix+current.path, strings.NewReader(current.body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.body != "" {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	if current.accept != "" {
+		request.Header.Set("Accept", current.accept)
+	}
+	if current.device != "" {
+		request.Header.Set(clientinfo.DeviceHeader, current.device)
+	}
+	// redirects are part of the contract, they must not be followed
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.StatusCode != current.status {
+		t.Fatalf("status %d, expected %d: %s", response.StatusCode, current.status, body)
+	}
+
+	mediaType, _, _ := strings.Cut(response.Header.Get("Content-Type"), ";")
+	content := spec.responseContent(t, current.method, request.URL.Path, response.StatusCode)
+	described, ok := content[mediaType]
+	if !ok {
+		for candidate := range content {
+			if prefix, wildcard := strings.CutSuffix(candidate, "/*"); wildcard && strings.HasPrefix(mediaType, prefix+"/") {
+				described, ok = content[candidate], true
+			}
+		}
+	}
+	if !ok {
+		t.Fatalf("openapi.json doesn't describe the %s response of %s %s", mediaType, current.method, request.URL.Path)
+	}
+
+	var errs []error
+	switch mediaType {
+	case "application/json":
+		errs = spec.validate(described["schema"], decodeJSON(t, body), "$", true)
+	case ndjsonMediaType:
+		scanner := bufio.NewScanner(bytes.NewReader(body))
+		for line := 0; scanner.Scan(); line++ {
+			errs = append(errs, spec.validate(described["itemSchema"], decodeJSON(t, scanner.Bytes()), fmt.Sprintf("$[%d]", line), true)...)
+		}
+	case sseMediaType:
+		itemSchema := described["itemSchema"].(map[string]any)
+		dataSchema := itemSchema["properties"].(map[string]any)["data"].(map[string]any)["contentSchema"]
+		for i, event := range strings.Split(strings.TrimSpace(string(body)), "\n\n") {
+			name, data, found := strings.Cut(event, "\n")
+			if !found || !strings.HasPrefix(name, "event: ") || !strings.HasPrefix(data, "data: ") {
+				t.Fatalf("malformed event %q", event)
+			}
+			item := map[string]any{"event": strings.TrimPrefix(name, "event: ")}
+			errs = append(errs, spec.validate(itemSchema, item, fmt.Sprintf("$[%d]", i), false)...)
+			errs = append(errs, spec.validate(dataSchema, decodeJSON(t, []byte(strings.TrimPrefix(data, "data: "))), fmt.Sprintf("$[%d].data", i), true)...)
+		}
+	default:
+		if len(body) == 0 {
+			errs = append(errs, fmt.Errorf("empty %s body", mediaType))
+		}
+	}
+	for _, err := range errs {
+		t.Error(err)
+	}
+
+	if response.StatusCode == http.StatusPermanentRedirect {
+		var moved struct {
+			Location string `json:"location"`
+		}
+		_ = json.Unmarshal(body, &moved)
+		if location := response.Header.Get("Location"); location == "" || location != moved.Location {
+			t.Errorf("Location header %q doesn't match the body %q", location, moved.Location)
+		}
+	}
+}
+
+func decodeJSON(t *testing.T, data []byte) any {
+	t.Helper()
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		t.Fatalf("invalid JSON %q: %v", data, err)
+	}
+	return value
+}
+
+func newContractHandler(t *testing.T, searchEnabled bool) http.Handler {
+	t.Helper()
+
+	var imageData bytes.Buffer
+	if err := png.Encode(&imageData, image.NewRGBA(image.Rect(0, 0, 4, 3))); err != nil {
+		t.Fatal(err)
+	}
+	docs := fstest.MapFS{
+		"docs/actions.yaml":   {Data: []byte("actions:\n  settings:\n    description: Opens the settings.\n    minAppVersion: 0.1.0\n")},
+		"docs/devices.yaml":   {Data: []byte("devices:\n  - id: xperia10iii\n    name: Sony Xperia 10 III\n    architecture: aarch64\n")},
+		"docs/redirects.yaml": {Data: []byte("redirects: []\n")},
+		"docs/en/index.md": {Data: []byte(`---
+title: Welcome
+description: The start of the guide.
+tags: [intro]
+keywords: [hello]
+actions: [settings]
+author: Someone
+lastReviewed: 2026-01-01
+difficulty: beginner
+---
+Welcome to the guide, open the settings using {{action settings}}.
+
+> Tip: Read everything.
+
+## Apps
+
+- See [the store](apps/store.md)
+- [x] done
+
+| Name | Value |
+|------|-------|
+| a    | 1     |
+
+` + "```bash\ndevel-su\n```\n")},
+		"docs/en/apps/store.md": {Data: []byte(`---
+title: Store
+aliases: [apps/old-store.md]
+tags: [apps]
+---
+The store has all the apps.
+
+![Store](store.png)
+`)},
+		"docs/en/apps/store.device-xperia10iii.md": {Data: []byte("---\ntitle: Store on the Xperia\n---\nThe store on the Xperia 10 III.\n")},
+		"docs/en/apps/store.png":                   {Data: imageData.Bytes()},
+		"docs/en/index.json": {Data: []byte(`{"source": "docs/en/index.md", "dim": 2, "chunks": [
+			{"index": 0, "text": "Welcome to the guide, open the settings.", "vector": [1, 0]}]}`)},
+		"docs/en/apps/store.json": {Data: []byte(`{"source": "docs/en/apps/store.md", "dim": 2, "chunks": [
+			{"index": 0, "text": "The store has all the apps.", "vector": [0, 1]}]}`)},
+	}
+
+	cfg := config.Default()
+	cfg.Search.Enabled = searchEnabled
+	cfg.Search.LexicalFallback = true
+
+	images, err := assets.NewCachedImageStore(docs, "docs", cfg.Cache.ImageEntries, cfg.Cache.ImageTTL.Std())
+	if err != nil {
+		t.Fatal(err)
+	}
+	extensions := []goldmark.Extender{markdown.NewImageAttributes(images)}
+	parser := content.NewCachedMarkdownParser(docs, markdown.New(extensions...), markdown.NewQML(extensions...), 100, cfg.Cache.ContentTTL.Std())
+	actions, err := content.LoadActionRegistry(docs, "docs/actions.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	devices, err := content.LoadDeviceRegistry(docs, "docs/devices.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parser.Redirects, err = content.LoadRedirects(docs, "docs", "docs/redirects.yaml", parser); err != nil {
+		t.Fatal(err)
+	}
+
+	searcher := search.NewService(docs, cfg.Search)
+	searcher.Metadata = search.ParserMetadata{Parser: parser}
+	readiness := health.NewChecker()
+	readiness.Add("docs", true, health.DocsCheck(docs, "docs", parser))
+
+	handler := NewHandler(parser, content.NewFSLocalizer(docs, "docs"), searcher, images, actions, devices, cfg, readiness)
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux, []string{"en"})
+	return clientinfo.Middleware(JSONErrors(mux))
+}
+
+// openAPIDocument validates values against the schemas of openapi.json, it supports the subset of JSON Schema
+// the document uses.
+type openAPIDocument struct {
+	paths   map[string]any
+	schemas map[string]any
+}
+
+func loadSpec(t *testing.T) *openAPIDocument {
+	t.Helper()
+	raw, ok := decodeJSON(t, openAPISpec).(map[string]any)
+	if !ok {
+		t.Fatal("openapi.json is not an object")
+	}
+	components, _ := raw["components"].(map[string]any)
+	schemas, _ := components["schemas"].(map[string]any)
+	paths, _ := raw["paths"].(map[string]any)
+	return &openAPIDocument{paths: paths, schemas: schemas}
+}
+
+var pathParameter = regexp.MustCompile(`\\\{[^}]+\\}`)
+
+// responseContent returns the described media types of the response, path parameters match anything
+// as {path} stands for the rest of the path.
+func (receiver *openAPIDocument) responseContent(t *testing.T, method string, requestPath string, status int) map[string]map[string]any {
+	t.Helper()
+	for template, item := range receiver.paths {
+		pattern := "^" + pathParameter.ReplaceAllString(regexp.QuoteMeta(template), ".+") + "$"
+		if !regexp.MustCompile(pattern).MatchString(requestPath) {
+			continue
+		}
+		operation, ok := item.(map[string]any)[strings.ToLower(method)].(map[string]any)
+		if !ok {
+			continue
+		}
+		response, ok := operation["responses"].(map[string]any)[fmt.Sprint(status)].(map[string]any)
+		if !ok {
+			t.Fatalf("openapi.json doesn't describe status %d of %s %s", status, method, template)
+		}
+		result := make(map[string]map[string]any)
+		for mediaType, described := range response["content"].(map[string]any) {
+			result[mediaType] = described.(map[string]any)
+		}
+		return result
+	}
+	t.Fatalf("openapi.json doesn't describe %s %s", method, requestPath)
+	return nil
+}
+
+// validate returns the differences between the value and the schema. When strict, objects must not have
+// properties the schema doesn't describe, so that new fields can't be added without documenting them.
+func (receiver *openAPIDocument) validate(schemaValue any, value any, at string, strict bool) []error {
+	schema, _ := schemaValue.(map[string]any)
+	if ref, ok := schema["$ref"].(string); ok {
+		return receiver.validate(receiver.schemas[strings.TrimPrefix(ref, "#/components/schemas/")], value, at, strict)
+	}
+
+	var errs []error
+	if allOf, ok := schema["allOf"].([]any); ok {
+		for _, part := range allOf {
+			errs = append(errs, receiver.validate(part, value, at, false)...)
+		}
+	}
+
+	if expected, ok := schema["type"].(string); ok && !hasType(value, expected) {
+		return append(errs, fmt.Errorf("%s: expected %s, got %T %v", at, expected, value, value))
+	}
+	if enum, ok := schema["enum"].([]any); ok && !slices.ContainsFunc(enum, func(allowed any) bool { return reflect.DeepEqual(allowed, value) }) {
+		errs = append(errs, fmt.Errorf("%s: %v is not one of %v", at, value, enum))
+	}
+	if minimum, ok := schema["minimum"].(json.Number); ok {
+		if number, isNumber := value.(json.Number); isNumber {
+			if actual, _ := number.Float64(); actual < mustFloat(minimum) {
+				errs = append(errs, fmt.Errorf("%s: %v is less than %v", at, number, minimum))
+			}
+		}
+	}
+
+	switch typed := value.(type) {
+	case []any:
+		for i, item := range typed {
+			errs = append(errs, receiver.validate(schema["items"], item, fmt.Sprintf("%s[%d]", at, i), true)...)
+		}
+	case map[string]any:
+		required, _ := schema["required"].([]any)
+		for _, name := range required {
+			if _, ok := typed[name.(string)]; !ok {
+				errs = append(errs, fmt.Errorf("%s: missing required property %s", at, name))
+			}
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		for name, property := range typed {
+			if described, ok := properties[name]; ok {
+				errs = append(errs, receiver.validate(described, property, at+"."+name, true)...)
+				continue
+			}
+			switch additional := schema["additionalProperties"].(type) {
+			case map[string]any:
+				errs = append(errs, receiver.validate(additional, property, at+"."+name, true)...)
+			case bool:
+				if !additional {
+					errs = append(errs, fmt.Errorf("%s: property %s is not allowed", at, name))
+				}
+			default:
+				if strict && !receiver.describes(schema, name) {
+					errs = append(errs, fmt.Errorf("%s: property %s is not described", at, name))
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// describes reports whether the schema or any of its allOf parts has the property.
+func (receiver *openAPIDocument) describes(schemaValue any, name string) bool {
+	schema, _ := schemaValue.(map[string]any)
+	if ref, ok := schema["$ref"].(string); ok {
+		return receiver.describes(receiver.schemas[strings.TrimPrefix(ref, "#/components/schemas/")], name)
+	}
+	if properties, ok := schema["properties"].(map[string]any); ok {
+		if _, ok := properties[name]; ok {
+			return true
+		}
+	}
+	if _, ok := schema["additionalProperties"]; ok {
+		return true
+	}
+	allOf, _ := schema["allOf"].([]any)
+	return slices.ContainsFunc(allOf, func(part any) bool { return receiver.describes(part, name) })
+}
+
+func hasType(value any, expected string) bool {
+	switch typed := value.(type) {
+	case string:
+		return expected == "string"
+	case bool:
+		return expected == "boolean"
+	case json.Number:
+		_, err := typed.Int64()
+		return expected == "number" || expected == "integer" && err == nil
+	case []any:
+		return expected == "array"
+	case map[string]any:
+		return expected == "object"
+	default:
+		return false
+	}
+}
+
+func mustFloat(number json.Number) float64 {
+	value, _ := number.Float64()
+	return value
+}
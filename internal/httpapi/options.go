@@ -0,0 +1,112 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"SfosBeginnerGuide/internal/clientinfo"
+	"SfosBeginnerGuide/internal/content"
+	"SfosBeginnerGuide/internal/search"
+)
+
+const (
+	astMediaType = "application/vnd.sfos-guide.ast+json"
+	qmlMediaType = "application/vnd.sfos-guide.qml+json"
+)
+
+// contentOptions reads the options from the request, devices which aren't in the registry are treated as unknown
+// so that they render the same content and share the cache entries.
+func contentOptions(request *http.Request, devices DeviceProvider) (content.Options, error) {
+	options := content.Options{
+		Format: content.FormatHTML,
+		Client: clientinfo.FromContext(request.Context()),
+	}
+	if options.Client.Device != "" {
+		if _, ok := devices.Get(options.Client.Device); !ok {
+			options.Client.Device = ""
+		}
+	}
+
+	if rawFormat := request.URL.Query().Get("format"); rawFormat != "" {
+		format, ok := content.ParseFormat(rawFormat)
+		if !ok {
+			return options, errors.New("unknown format " + rawFormat)
+		}
+		options.Format = format
+	} else if accept := request.Header.Get("Accept"); strings.Contains(accept, astMediaType) {
+		options.Format = content.FormatAST
+	} else if strings.Contains(accept, qmlMediaType) {
+		options.Format = content.FormatQML
+	}
+
+	return options, nil
+}
+
+type searchRequest struct {
+	Query   string         `json:"query"`
+	Top     *int           `json:"top"`
+	Filters search.Filters `json:"filters"`
+	Sort    string         `json:"sort"`
+	Cursor  string         `json:"cursor"`
+}
+
+// parseSearchRequest reads the search from the query string of GET requests and from the JSON body
+// of QUERY and POST requests. When the request is invalid, the returned problem is the message for the client.
+func parseSearchRequest(request *http.Request) (body *searchRequest, problem string) {
+	body = &searchRequest{}
+
+	if request.Method == http.MethodGet {
+		query := request.URL.Query()
+		body.Query = query.Get("q")
+		if rawTop := query.Get("top"); rawTop != "" {
+			top, err := strconv.Atoi(rawTop)
+			if err != nil {
+				return nil, "Invalid query parameter: top"
+			}
+			body.Top = &top
+		}
+		if strings.TrimSpace(body.Query) == "" {
+			return nil, "Missing query parameter: q"
+		}
+		body.Filters.PathPrefix = query.Get("path")
+		body.Filters.Tags = query["tag"]
+		if rawHasActions := query.Get("hasActions"); rawHasActions != "" {
+			hasActions, err := strconv.ParseBool(rawHasActions)
+			if err != nil {
+				return nil, "Invalid query parameter: hasActions"
+			}
+			body.Filters.HasActions = &hasActions
+		}
+		body.Filters.Difficulty = query.Get("difficulty")
+		body.Sort = query.Get("sort")
+		body.Cursor = query.Get("cursor")
+	} else {
+		if err := json.NewDecoder(request.Body).Decode(body); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, "Missing JSON body"
+			}
+			return nil, "Invalid JSON body"
+		}
+		if strings.TrimSpace(body.Query) == "" {
+			return nil, "Missing body field: query"
+		}
+	}
+
+	switch body.Sort {
+	case "", search.SortRelevance, search.SortTitle, search.SortPath:
+	default:
+		return nil, "Invalid sort"
+	}
+	switch body.Filters.Difficulty {
+	case "", content.DifficultyBeginner, content.DifficultyIntermediate, content.DifficultyAdvanced:
+	default:
+		return nil, "Invalid filter: difficulty"
+	}
+
+	body.Query = strings.TrimSpace(body.Query)
+	return body, ""
+}
@@ -0,0 +1,132 @@
+package httpapi
+
+import (
+	"net/http"
+	"strings"
+
+	"SfosBeginnerGuide/internal/apiv1"
+	"SfosBeginnerGuide/internal/httpx"
+)
+
+const APIPrefix = "/api/v1"
+
+type route struct {
+	method  string
+	path    string
+	handler func(*Handler, http.ResponseWriter, *http.Request)
+}
+
+// apiRoutes are served under the /api/v1 prefix and, apart from content, also at the original URLs
+// the released apps use. Every one of them has to be described in openapi.json.
+var apiRoutes = []route{
+	{http.MethodGet, "/languages", (*Handler).LanguagesList},
+	{http.MethodGet, "/capabilities", (*Handler).Capabilities},
+	{http.MethodGet, "/devices", (*Handler).DevicesList},
+	{"QUERY", "/search/{lang}", (*Handler).Search},
+	{http.MethodGet, "/search/{lang}", (*Handler).Search},
+	{http.MethodPost, "/search/{lang}", (*Handler).Search},
+	{http.MethodGet, "/assets/{path...}", (*Handler).Asset},
+	{http.MethodGet, "/content/{lang}/{path...}", (*Handler).Content},
+}
+
+// RegisterRoutes registers the versioned endpoints and the compatibility ones with the unversioned bodies.
+// Content at the original URLs is only routed for the known languages, anything else never reaches the parser.
+func (receiver *Handler) RegisterRoutes(mux *http.ServeMux, languages []string) {
+	mux.HandleFunc("GET /healthz", receiver.Liveness)
+	mux.HandleFunc("GET /readyz", receiver.Readiness)
+	mux.HandleFunc("GET /robots.txt", robots)
+	mux.HandleFunc("GET /favicon.ico", noContent)
+	mux.HandleFunc("GET /openapi.json", openAPI)
+	mux.HandleFunc("GET "+APIPrefix+"/openapi.json", openAPI)
+
+	v1 := receiver.withPresenter(apiv1.Presenter{})
+	for _, current := range apiRoutes {
+		mux.HandleFunc(current.method+" "+APIPrefix+current.path, bind(v1, current.handler))
+		if current.path != "/content/{lang}/{path...}" {
+			mux.HandleFunc(current.method+" "+current.path, bind(receiver, current.handler))
+		}
+	}
+
+	for _, language := range languages {
+		mux.HandleFunc("GET /"+language, withPathValue("lang", language, receiver.Content))
+		mux.HandleFunc("GET /"+language+"/{path...}", withPathValue("lang", language, receiver.Content))
+	}
+}
+
+func bind(handler *Handler, method func(*Handler, http.ResponseWriter, *http.Request)) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		method(handler, writer, request)
+	}
+}
+
+// JSONErrors replaces the plain text 404 and 405 responses of the mux with JSON ones,
+// the Allow header set by the mux is kept.
+func JSONErrors(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if _, pattern := mux.Handler(request); pattern != "" {
+			mux.ServeHTTP(writer, request)
+			return
+		}
+
+		mux.ServeHTTP(&muxErrorWriter{ResponseWriter: writer}, request)
+	})
+}
+
+type muxErrorWriter struct {
+	http.ResponseWriter
+	replaced bool
+}
+
+func (receiver *muxErrorWriter) WriteHeader(status int) {
+	var message string
+	switch status {
+	case http.StatusNotFound:
+		message = "No content could be found at the requested URL"
+	case http.StatusMethodNotAllowed:
+		message = "Method not allowed"
+	default:
+		receiver.ResponseWriter.WriteHeader(status)
+		return
+	}
+
+	receiver.replaced = true
+	receiver.Header().Del("Content-Type")
+	receiver.Header().Del("X-Content-Type-Options")
+	httpx.WriteJSON(status, NewErrorResponse(message), receiver.ResponseWriter)
+}
+
+func (receiver *muxErrorWriter) Write(data []byte) (int, error) {
+	if receiver.replaced {
+		return len(data), nil
+	}
+	return receiver.ResponseWriter.Write(data)
+}
+
+// contentURL returns the URL of a page using the same API version and query string as the request.
+func contentURL(request *http.Request, pagePath string) string {
+	location := "/" + pagePath
+	if strings.HasPrefix(request.URL.Path, APIPrefix+"/") {
+		location = APIPrefix + "/content" + location
+	}
+	if request.URL.RawQuery != "" {
+		location += "?" + request.URL.RawQuery
+	}
+	return location
+}
+
+func withPathValue(name string, value string, next http.HandlerFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		request.SetPathValue(name, value)
+		next(writer, request)
+	}
+}
+
+func robots(writer http.ResponseWriter, _ *http.Request) {
+	writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	writer.WriteHeader(http.StatusOK)
+	_, _ = writer.Write([]byte("User-agent: *\nDisallow: /\n"))
+}
+
+func noContent(writer http.ResponseWriter, _ *http.Request) {
+	writer.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,73 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	ndjsonMediaType = "application/x-ndjson"
+	sseMediaType    = "text/event-stream"
+)
+
+// streamMediaType returns the streaming format the client accepts, or an empty string for a regular response.
+func streamMediaType(request *http.Request) string {
+	accept := request.Header.Get("Accept")
+	switch {
+	case strings.Contains(accept, sseMediaType):
+		return sseMediaType
+	case strings.Contains(accept, ndjsonMediaType):
+		return ndjsonMediaType
+	default:
+		return ""
+	}
+}
+
+// eventStream writes JSON events either as NDJSON lines or as Server-Sent Events, each event is flushed
+// right away. The headers are only sent with the first event, until then a regular error response is possible.
+type eventStream struct {
+	writer     http.ResponseWriter
+	controller *http.ResponseController
+	mediaType  string
+	started    bool
+}
+
+func newEventStream(writer http.ResponseWriter, mediaType string) *eventStream {
+	return &eventStream{
+		writer:     writer,
+		controller: http.NewResponseController(writer),
+		mediaType:  mediaType,
+	}
+}
+
+func (receiver *eventStream) Send(event string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed encoding %s event: %w", event, err)
+	}
+
+	if !receiver.started {
+		receiver.writer.Header().Set("Content-Type", receiver.mediaType)
+		// stops proxies like nginx from buffering the whole response
+		receiver.writer.Header().Set("X-Accel-Buffering", "no")
+		receiver.writer.WriteHeader(http.StatusOK)
+		receiver.started = true
+	}
+
+	if receiver.mediaType == sseMediaType {
+		_, err = fmt.Fprintf(receiver.writer, "event: %s\ndata: %s\n\n", event, data)
+	} else {
+		_, err = receiver.writer.Write(append(data, '\n'))
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := receiver.controller.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package httpx
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// NewLogHandler wraps a slog handler so that every record logged with a request context
+// carries the request id.
+func NewLogHandler(handler slog.Handler) slog.Handler {
+	return &requestIDLogHandler{Handler: handler}
+}
+
+type requestIDLogHandler struct {
+	slog.Handler
+}
+
+func (receiver *requestIDLogHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	return receiver.Handler.Handle(ctx, record)
+}
+
+func (receiver *requestIDLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &requestIDLogHandler{Handler: receiver.Handler.WithAttrs(attrs)}
+}
+
+func (receiver *requestIDLogHandler) WithGroup(name string) slog.Handler {
+	return &requestIDLogHandler{Handler: receiver.Handler.WithGroup(name)}
+}
+
+// LoggingMiddleware assigns every request an id (or keeps the one sent in X-Request-ID), returns it in
+// the response and logs one line per request. It should wrap the mux as closely as possible,
+// otherwise the matched route is not known.
+func LoggingMiddleware(logger *slog.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		id := request.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		writer.Header().Set(RequestIDHeader, id)
+
+		request = request.WithContext(WithRequestID(request.Context(), id))
+		recorder := NewResponseRecorder(writer)
+		start := time.Now()
+
+		next.ServeHTTP(recorder, request)
+
+		level := slog.LevelInfo
+		if recorder.Status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		logger.LogAttrs(
+			request.Context(),
+			level,
+			"request",
+			slog.String("method", request.Method),
+			slog.String("path", request.URL.Path),
+			slog.String("route", request.Pattern),
+			slog.Int("status", recorder.Status),
+			slog.Int("bytes", recorder.Bytes),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("user_agent", request.UserAgent()),
+		)
+	})
+}
@@ -0,0 +1,35 @@
+package httpx
+
+import "net/http"
+
+// ResponseRecorder remembers the status and size of a response for middlewares that report on it.
+type ResponseRecorder struct {
+	http.ResponseWriter
+	Status int
+	Bytes  int
+
+	wroteHeader bool
+}
+
+func NewResponseRecorder(writer http.ResponseWriter) *ResponseRecorder {
+	return &ResponseRecorder{ResponseWriter: writer, Status: http.StatusOK}
+}
+
+func (receiver *ResponseRecorder) WriteHeader(status int) {
+	if !receiver.wroteHeader {
+		receiver.Status = status
+		receiver.wroteHeader = true
+	}
+	receiver.ResponseWriter.WriteHeader(status)
+}
+
+func (receiver *ResponseRecorder) Write(data []byte) (int, error) {
+	receiver.wroteHeader = true
+	written, err := receiver.ResponseWriter.Write(data)
+	receiver.Bytes += written
+	return written, err
+}
+
+func (receiver *ResponseRecorder) Unwrap() http.ResponseWriter {
+	return receiver.ResponseWriter
+}
@@ -0,0 +1,143 @@
+package markdown
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+const ActionScheme = "action://"
+
+var ActionsContextKey = parser.NewContextKey()
+
+var KindAction = ast.NewNodeKind("Action")
+
+// Action is an inline placeholder for an action the app can perform, written either as
+// a `{{action settings}}` shortcode (optionally followed by a label) or as a link to `action://settings`.
+type Action struct {
+	ast.BaseInline
+	ActionID string
+}
+
+func (receiver *Action) Kind() ast.NodeKind {
+	return KindAction
+}
+
+func (receiver *Action) Dump(source []byte, level int) {
+	ast.DumpHelper(receiver, source, level, map[string]string{"ActionID": receiver.ActionID}, nil)
+}
+
+func newActions() goldmark.Extender { return actions{} }
+
+type actions struct{}
+
+func (actions) Extend(md goldmark.Markdown) {
+	md.Parser().AddOptions(
+		parser.WithInlineParsers(
+			util.Prioritized(actionParser{}, 150),
+		),
+		parser.WithASTTransformers(
+			util.Prioritized(actionTransformer{}, 180),
+		),
+	)
+	md.Renderer().AddOptions(
+		renderer.WithNodeRenderers(
+			util.Prioritized(actionRenderer{}, 500),
+		),
+	)
+}
+
+var actionShortcodeStart = []byte("{{action ")
+
+type actionParser struct{}
+
+func (actionParser) Trigger() []byte {
+	return []byte{'{'}
+}
+
+func (actionParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, _ := block.PeekLine()
+	if !bytes.HasPrefix(line, actionShortcodeStart) {
+		return nil
+	}
+	end := bytes.Index(line, []byte("}}"))
+	if end < 0 {
+		return nil
+	}
+
+	id, label, _ := strings.Cut(strings.TrimSpace(string(line[len(actionShortcodeStart):end])), " ")
+	if id == "" {
+		return nil
+	}
+	block.Advance(end + 2)
+
+	label = strings.TrimSpace(label)
+	if label == "" {
+		label = id
+	}
+	node := &Action{ActionID: id}
+	node.AppendChild(node, ast.NewString([]byte(label)))
+
+	return node
+}
+
+type actionTransformer struct{}
+
+func (actionTransformer) Transform(node *ast.Document, reader text.Reader, parserContext parser.Context) {
+	var links []*ast.Link
+	var ids []string
+	_ = ast.Walk(node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch typed := n.(type) {
+		case *ast.Link:
+			if strings.HasPrefix(string(typed.Destination), ActionScheme) {
+				links = append(links, typed)
+			}
+		case *Action:
+			ids = append(ids, typed.ActionID)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, link := range links {
+		action := &Action{ActionID: strings.TrimPrefix(string(link.Destination), ActionScheme)}
+		for child := link.FirstChild(); child != nil; {
+			next := child.NextSibling()
+			action.AppendChild(action, child)
+			child = next
+		}
+		link.Parent().ReplaceChild(link.Parent(), link, action)
+		ids = append(ids, action.ActionID)
+	}
+
+	parserContext.Set(ActionsContextKey, ids)
+}
+
+type actionRenderer struct{}
+
+func (receiver actionRenderer) RegisterFuncs(registerer renderer.NodeRendererFuncRegisterer) {
+	registerer.Register(KindAction, receiver.renderAction)
+}
+
+func (actionRenderer) renderAction(writer util.BufWriter, _ []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		_, _ = writer.WriteString("</a>")
+		return ast.WalkContinue, nil
+	}
+
+	id := util.EscapeHTML([]byte(node.(*Action).ActionID))
+	_, _ = writer.WriteString(`<a class="action" data-action="`)
+	_, _ = writer.Write(id)
+	_, _ = writer.WriteString(`" href="` + ActionScheme)
+	_, _ = writer.Write(id)
+	_, _ = writer.WriteString(`">`)
+	return ast.WalkContinue, nil
+}
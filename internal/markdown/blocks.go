@@ -0,0 +1,275 @@
+package markdown
+
+import (
+	"bytes"
+
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+)
+
+const (
+	BlockHeading       = "heading"
+	BlockParagraph     = "paragraph"
+	BlockList          = "list"
+	BlockListItem      = "listItem"
+	BlockCode          = "code"
+	BlockTable         = "table"
+	BlockQuote         = "quote"
+	BlockCallout       = "callout"
+	BlockContainer     = "container"
+	BlockThematicBreak = "thematicBreak"
+	BlockHTML          = "html"
+
+	InlineText          = "text"
+	InlineStrong        = "strong"
+	InlineEmphasis      = "emphasis"
+	InlineStrikethrough = "strikethrough"
+	InlineCode          = "code"
+	InlineLink          = "link"
+	InlineImage         = "image"
+	InlineAction        = "action"
+	InlineLineBreak     = "lineBreak"
+	InlineHTML          = "html"
+)
+
+// Block is a node of the structured (non-HTML) representation of a document.
+// Only the fields relevant for the given Type are filled.
+type Block struct {
+	Type      string      `json:"type"`
+	Level     int         `json:"level,omitempty"`
+	Ordered   bool        `json:"ordered,omitempty"`
+	Start     int         `json:"start,omitempty"`
+	Checked   *bool       `json:"checked,omitempty"`
+	Language  string      `json:"language,omitempty"`
+	Kind      string      `json:"kind,omitempty"`
+	Condition string      `json:"condition,omitempty"`
+	Text      string      `json:"text,omitempty"`
+	Inlines   []*Inline   `json:"inlines,omitempty"`
+	Children  []*Block    `json:"children,omitempty"`
+	Rows      []*TableRow `json:"rows,omitempty"`
+}
+
+type TableRow struct {
+	Header bool         `json:"header,omitempty"`
+	Cells  []*TableCell `json:"cells"`
+}
+
+type TableCell struct {
+	Align   string    `json:"align,omitempty"`
+	Inlines []*Inline `json:"inlines,omitempty"`
+}
+
+type Inline struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	Href     string    `json:"href,omitempty"`
+	Title    string    `json:"title,omitempty"`
+	Action   string    `json:"action,omitempty"`
+	Children []*Inline `json:"children,omitempty"`
+}
+
+func BuildBlocks(source []byte, nodes []ast.Node) []*Block {
+	result := make([]*Block, 0, len(nodes))
+	for _, node := range nodes {
+		if block := buildBlock(source, node); block != nil {
+			result = append(result, block)
+		}
+	}
+	return result
+}
+
+func buildChildBlocks(source []byte, parent ast.Node) []*Block {
+	var result []*Block
+	for child := parent.FirstChild(); child != nil; child = child.NextSibling() {
+		if block := buildBlock(source, child); block != nil {
+			result = append(result, block)
+		}
+	}
+	return result
+}
+
+func buildBlock(source []byte, node ast.Node) *Block {
+	switch typed := node.(type) {
+	case *ast.Heading:
+		return &Block{Type: BlockHeading, Level: typed.Level, Inlines: buildInlines(source, typed)}
+	case *ast.Paragraph, *ast.TextBlock:
+		return &Block{Type: BlockParagraph, Inlines: buildInlines(source, typed)}
+	case *ast.List:
+		block := &Block{Type: BlockList, Ordered: typed.IsOrdered()}
+		if typed.IsOrdered() {
+			block.Start = typed.Start
+		}
+		block.Children = buildChildBlocks(source, typed)
+		return block
+	case *ast.ListItem:
+		block := &Block{Type: BlockListItem, Children: buildChildBlocks(source, typed)}
+		block.Checked = taskState(typed)
+		return block
+	case *ast.FencedCodeBlock:
+		return &Block{Type: BlockCode, Language: string(typed.Language(source)), Text: linesText(source, typed)}
+	case *ast.CodeBlock:
+		return &Block{Type: BlockCode, Text: linesText(source, typed)}
+	case *ast.Blockquote:
+		return &Block{Type: BlockQuote, Children: buildChildBlocks(source, typed)}
+	case *Callout:
+		return &Block{Type: BlockCallout, Kind: typed.CalloutKind, Children: buildChildBlocks(source, typed)}
+	case *Container:
+		block := &Block{Type: BlockContainer, Kind: typed.Name, Children: buildChildBlocks(source, typed)}
+		if typed.Name == ConditionalContainer {
+			block.Condition = typed.Args
+		}
+		return block
+	case *ast.ThematicBreak:
+		return &Block{Type: BlockThematicBreak}
+	case *ast.HTMLBlock:
+		return &Block{Type: BlockHTML, Text: linesText(source, typed)}
+	case *east.Table:
+		return buildTable(source, typed)
+	}
+
+	return nil
+}
+
+func buildTable(source []byte, table *east.Table) *Block {
+	block := &Block{Type: BlockTable}
+	for row := table.FirstChild(); row != nil; row = row.NextSibling() {
+		_, isHeader := row.(*east.TableHeader)
+		tableRow := &TableRow{Header: isHeader}
+		for cell := row.FirstChild(); cell != nil; cell = cell.NextSibling() {
+			tableCell := &TableCell{Inlines: buildInlines(source, cell)}
+			if typed, ok := cell.(*east.TableCell); ok && typed.Alignment != east.AlignNone {
+				tableCell.Align = typed.Alignment.String()
+			}
+			tableRow.Cells = append(tableRow.Cells, tableCell)
+		}
+		block.Rows = append(block.Rows, tableRow)
+	}
+	return block
+}
+
+func buildInlines(source []byte, parent ast.Node) []*Inline {
+	var result []*Inline
+	for child := parent.FirstChild(); child != nil; child = child.NextSibling() {
+		result = appendInline(result, buildInline(source, child)...)
+	}
+	return result
+}
+
+func buildInline(source []byte, node ast.Node) []*Inline {
+	switch typed := node.(type) {
+	case *ast.Text:
+		inlines := []*Inline{{Type: InlineText, Text: string(typed.Value(source))}}
+		if typed.HardLineBreak() {
+			inlines = append(inlines, &Inline{Type: InlineLineBreak})
+		} else if typed.SoftLineBreak() {
+			inlines = append(inlines, &Inline{Type: InlineText, Text: " "})
+		}
+		return inlines
+	case *ast.String:
+		return []*Inline{{Type: InlineText, Text: string(typed.Value)}}
+	case *ast.CodeSpan:
+		return []*Inline{{Type: InlineCode, Text: string(inlineText(source, typed))}}
+	case *ast.Emphasis:
+		inlineType := InlineEmphasis
+		if typed.Level >= 2 {
+			inlineType = InlineStrong
+		}
+		return []*Inline{{Type: inlineType, Children: buildInlines(source, typed)}}
+	case *east.Strikethrough:
+		return []*Inline{{Type: InlineStrikethrough, Children: buildInlines(source, typed)}}
+	case *ast.Link:
+		return []*Inline{{
+			Type:     InlineLink,
+			Href:     string(typed.Destination),
+			Title:    string(typed.Title),
+			Children: buildInlines(source, typed),
+		}}
+	case *Action:
+		return []*Inline{{
+			Type:     InlineAction,
+			Action:   typed.ActionID,
+			Href:     ActionScheme + typed.ActionID,
+			Children: buildInlines(source, typed),
+		}}
+	case *ast.AutoLink:
+		return []*Inline{{
+			Type:     InlineLink,
+			Href:     string(typed.URL(source)),
+			Children: []*Inline{{Type: InlineText, Text: string(typed.Label(source))}},
+		}}
+	case *ast.Image:
+		return []*Inline{{
+			Type:  InlineImage,
+			Href:  string(typed.Destination),
+			Title: string(typed.Title),
+			Text:  string(inlineText(source, typed)),
+		}}
+	case *ast.RawHTML:
+		var buf bytes.Buffer
+		for i := 0; i < typed.Segments.Len(); i++ {
+			segment := typed.Segments.At(i)
+			buf.Write(segment.Value(source))
+		}
+		return []*Inline{{Type: InlineHTML, Text: buf.String()}}
+	case *east.TaskCheckBox:
+		return nil
+	}
+
+	return buildInlines(source, node)
+}
+
+// appendInline appends the inlines while merging neighbouring plain text nodes.
+func appendInline(target []*Inline, inlines ...*Inline) []*Inline {
+	for _, inline := range inlines {
+		if inline == nil {
+			continue
+		}
+		if len(target) > 0 && inline.Type == InlineText && target[len(target)-1].Type == InlineText {
+			target[len(target)-1].Text += inline.Text
+			continue
+		}
+		target = append(target, inline)
+	}
+	return target
+}
+
+func taskState(item *ast.ListItem) *bool {
+	first := item.FirstChild()
+	if first == nil {
+		return nil
+	}
+	checkBox, ok := first.FirstChild().(*east.TaskCheckBox)
+	if !ok {
+		return nil
+	}
+	checked := checkBox.IsChecked
+	return &checked
+}
+
+func linesText(source []byte, node ast.Node) string {
+	var buf bytes.Buffer
+	lines := node.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		segment := lines.At(i)
+		buf.Write(segment.Value(source))
+	}
+	return buf.String()
+}
+
+func inlineText(source []byte, node ast.Node) []byte {
+	var buf bytes.Buffer
+	for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+		switch typed := child.(type) {
+		case *ast.Text:
+			buf.Write(typed.Value(source))
+			if typed.SoftLineBreak() || typed.HardLineBreak() {
+				buf.WriteByte(' ')
+			}
+		case *ast.String:
+			buf.Write(typed.Value)
+		default:
+			buf.Write(inlineText(source, child))
+		}
+	}
+	return buf.Bytes()
+}
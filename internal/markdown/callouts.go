@@ -0,0 +1,170 @@
+package markdown
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+const (
+	CalloutTip     = "tip"
+	CalloutNote    = "note"
+	CalloutWarning = "warning"
+)
+
+var CalloutKinds = []string{CalloutTip, CalloutNote, CalloutWarning}
+
+var KindCallout = ast.NewNodeKind("Callout")
+
+// Callout is a typed block created either from a blockquote starting with a label (`> Warning: ...`)
+// or from a container named after the callout kind (`:::warning`).
+type Callout struct {
+	ast.BaseBlock
+	CalloutKind string
+}
+
+func (receiver *Callout) Kind() ast.NodeKind {
+	return KindCallout
+}
+
+func (receiver *Callout) Dump(source []byte, level int) {
+	ast.DumpHelper(receiver, source, level, map[string]string{"CalloutKind": receiver.CalloutKind}, nil)
+}
+
+func newCallouts() goldmark.Extender { return callouts{} }
+
+type callouts struct{}
+
+func (callouts) Extend(md goldmark.Markdown) {
+	md.Parser().AddOptions(
+		parser.WithASTTransformers(
+			util.Prioritized(calloutTransformer{}, 170),
+		),
+	)
+	md.Renderer().AddOptions(
+		renderer.WithNodeRenderers(
+			util.Prioritized(calloutRenderer{}, 500),
+		),
+	)
+}
+
+type calloutTransformer struct{}
+
+func (calloutTransformer) Transform(node *ast.Document, reader text.Reader, parserContext parser.Context) {
+	var replacements []ast.Node
+	_ = ast.Walk(node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch typed := n.(type) {
+		case *Container:
+			if isCalloutKind(typed.Name) {
+				replacements = append(replacements, n)
+			}
+		case *ast.Blockquote:
+			if blockquoteCalloutKind(typed, reader.Source()) != "" {
+				replacements = append(replacements, n)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, original := range replacements {
+		callout := &Callout{}
+		switch typed := original.(type) {
+		case *Container:
+			callout.CalloutKind = typed.Name
+		case *ast.Blockquote:
+			callout.CalloutKind = blockquoteCalloutKind(typed, reader.Source())
+			stripBlockquoteLabel(typed, reader.Source())
+		}
+
+		for child := original.FirstChild(); child != nil; {
+			next := child.NextSibling()
+			callout.AppendChild(callout, child)
+			child = next
+		}
+		original.Parent().ReplaceChild(original.Parent(), original, callout)
+	}
+}
+
+func isCalloutKind(kind string) bool {
+	for _, known := range CalloutKinds {
+		if kind == known {
+			return true
+		}
+	}
+	return false
+}
+
+func blockquoteLabel(quote *ast.Blockquote) *ast.Text {
+	paragraph, ok := quote.FirstChild().(*ast.Paragraph)
+	if !ok {
+		return nil
+	}
+	label, _ := paragraph.FirstChild().(*ast.Text)
+	return label
+}
+
+func blockquoteCalloutKind(quote *ast.Blockquote, source []byte) string {
+	label := blockquoteLabel(quote)
+	if label == nil {
+		return ""
+	}
+	kind, _, found := strings.Cut(string(label.Value(source)), ":")
+	if !found {
+		return ""
+	}
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	if !isCalloutKind(kind) {
+		return ""
+	}
+	return kind
+}
+
+func stripBlockquoteLabel(quote *ast.Blockquote, source []byte) {
+	label := blockquoteLabel(quote)
+	paragraph := label.Parent()
+	value := label.Value(source)
+	label.Segment = label.Segment.WithStart(label.Segment.Start + bytes.IndexByte(value, ':') + 1)
+
+	// the label can be split into several text nodes, for example linkify splits "Warning: text" after the colon
+	for {
+		text, ok := paragraph.FirstChild().(*ast.Text)
+		if !ok {
+			break
+		}
+		text.Segment = text.Segment.TrimLeftSpace(source)
+		if !text.Segment.IsEmpty() {
+			break
+		}
+		paragraph.RemoveChild(paragraph, text)
+	}
+
+	if !paragraph.HasChildren() {
+		quote.RemoveChild(quote, paragraph)
+	}
+}
+
+type calloutRenderer struct{}
+
+func (receiver calloutRenderer) RegisterFuncs(registerer renderer.NodeRendererFuncRegisterer) {
+	registerer.Register(KindCallout, receiver.renderCallout)
+}
+
+func (calloutRenderer) renderCallout(writer util.BufWriter, _ []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		_, _ = writer.WriteString("</div>\n")
+		return ast.WalkContinue, nil
+	}
+
+	kind := node.(*Callout).CalloutKind
+	_, _ = writer.WriteString(`<div class="callout callout-` + kind + `" data-callout="` + kind + `">` + "\n")
+	return ast.WalkContinue, nil
+}
@@ -0,0 +1,88 @@
+package markdown
+
+import (
+	"fmt"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+const ConditionalContainer = "if"
+
+var (
+	ConditionContextKey       = parser.NewContextKey()
+	ConditionErrorsContextKey = parser.NewContextKey()
+)
+
+type ConditionEvaluator interface {
+	Evaluate(expression string) (matched bool, known bool, err error)
+}
+
+func newConditionals() goldmark.Extender { return conditionals{} }
+
+type conditionals struct{}
+
+func (conditionals) Extend(md goldmark.Markdown) {
+	md.Parser().AddOptions(
+		parser.WithASTTransformers(
+			util.Prioritized(conditionalTransformer{}, 140),
+		),
+	)
+}
+
+// conditionalTransformer resolves `:::if <expression>` containers: content whose condition does not match
+// the client is removed, matching content is unwrapped and content the client did not provide enough
+// information for is kept in the container, so it can be flagged.
+type conditionalTransformer struct{}
+
+func (conditionalTransformer) Transform(node *ast.Document, reader text.Reader, parserContext parser.Context) {
+	evaluator, _ := parserContext.Get(ConditionContextKey).(ConditionEvaluator)
+	if evaluator == nil {
+		return
+	}
+
+	var containers []*Container
+	_ = ast.Walk(node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if container, ok := n.(*Container); ok && entering && container.Name == ConditionalContainer {
+			containers = append(containers, container)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	var errs []error
+	// walked in reverse so nested containers are resolved before their parents are unwrapped or removed
+	for i := len(containers) - 1; i >= 0; i-- {
+		container := containers[i]
+		parent := container.Parent()
+		if parent == nil {
+			continue
+		}
+
+		matched, known, err := evaluator.Evaluate(container.Args)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid condition %q: %w", container.Args, err))
+			continue
+		}
+		if !known {
+			continue
+		}
+		if !matched {
+			parent.RemoveChild(parent, container)
+			continue
+		}
+
+		for child := container.FirstChild(); child != nil; {
+			next := child.NextSibling()
+			parent.InsertBefore(parent, container, child)
+			child = next
+		}
+		parent.RemoveChild(parent, container)
+	}
+
+	if len(errs) > 0 {
+		parserContext.Set(ConditionErrorsContextKey, errs)
+	}
+}
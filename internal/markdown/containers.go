@@ -0,0 +1,147 @@
+package markdown
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+var KindContainer = ast.NewNodeKind("Container")
+
+// Container is a fenced block of markdown in the form of:
+//
+//	:::name arguments
+//	content
+//	:::
+//
+// Containers can be nested. Other extensions give them meaning based on the name, containers
+// nobody claimed are rendered as their plain content.
+type Container struct {
+	ast.BaseBlock
+	Name string
+	Args string
+
+	open bool
+}
+
+func (receiver *Container) Kind() ast.NodeKind {
+	return KindContainer
+}
+
+func (receiver *Container) Dump(source []byte, level int) {
+	ast.DumpHelper(receiver, source, level, map[string]string{"Name": receiver.Name, "Args": receiver.Args}, nil)
+}
+
+func newContainers() goldmark.Extender { return containers{} }
+
+type containers struct{}
+
+func (containers) Extend(md goldmark.Markdown) {
+	md.Parser().AddOptions(
+		parser.WithBlockParsers(
+			util.Prioritized(containerParser{}, 50),
+		),
+	)
+	md.Renderer().AddOptions(
+		renderer.WithNodeRenderers(
+			util.Prioritized(containerRenderer{}, 500),
+		),
+	)
+}
+
+type containerParser struct{}
+
+func (containerParser) Trigger() []byte {
+	return []byte{':'}
+}
+
+func (containerParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	pos := pc.BlockOffset()
+	if pos < 0 || !bytes.HasPrefix(line[pos:], []byte(":::")) {
+		return nil, parser.NoChildren
+	}
+
+	header := strings.TrimSpace(strings.TrimLeft(string(line[pos:]), ":"))
+	if header == "" {
+		return nil, parser.NoChildren
+	}
+	name, args, _ := strings.Cut(header, " ")
+
+	reader.Advance(segment.Len() - trailingNewline(line))
+
+	return &Container{Name: strings.ToLower(name), Args: strings.TrimSpace(args), open: true}, parser.HasChildren
+}
+
+func (containerParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	line, segment := reader.PeekLine()
+	if isContainerEnd(line) && !hasOpenContainer(node) {
+		reader.Advance(segment.Len() - trailingNewline(line))
+		return parser.Close
+	}
+
+	return parser.Continue | parser.HasChildren
+}
+
+func (containerParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {
+	node.(*Container).open = false
+}
+
+func (containerParser) CanInterruptParagraph() bool {
+	return true
+}
+
+func (containerParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+func isContainerEnd(line []byte) bool {
+	trimmed := bytes.TrimSpace(line)
+	return len(trimmed) >= 3 && len(bytes.Trim(trimmed, ":")) == 0
+}
+
+// hasOpenContainer reports whether a nested container is still open, in which case the end marker belongs to it.
+func hasOpenContainer(node ast.Node) bool {
+	for child := node.LastChild(); child != nil; child = child.LastChild() {
+		if container, ok := child.(*Container); ok && container.open {
+			return true
+		}
+	}
+	return false
+}
+
+func trailingNewline(line []byte) int {
+	if len(line) > 0 && line[len(line)-1] == '\n' {
+		return 1
+	}
+	return 0
+}
+
+type containerRenderer struct{}
+
+func (receiver containerRenderer) RegisterFuncs(registerer renderer.NodeRendererFuncRegisterer) {
+	registerer.Register(KindContainer, receiver.renderContainer)
+}
+
+func (containerRenderer) renderContainer(writer util.BufWriter, _ []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	container := node.(*Container)
+	if container.Name != ConditionalContainer {
+		return ast.WalkContinue, nil
+	}
+
+	// conditional content still present after the transformation could not be evaluated for the client
+	if entering {
+		_, _ = writer.WriteString(`<div data-condition="`)
+		_, _ = writer.Write(util.EscapeHTML([]byte(container.Args)))
+		_, _ = writer.WriteString("\">\n")
+	} else {
+		_, _ = writer.WriteString("</div>\n")
+	}
+	return ast.WalkContinue, nil
+}
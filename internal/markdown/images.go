@@ -0,0 +1,118 @@
+package markdown
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+const AssetsPrefix = "/assets/"
+
+type ImageProvider interface {
+	Dimensions(imagePath string) (width int, height int, ok bool)
+	Widths(imagePath string) []int
+}
+
+func NewImageAttributes(provider ImageProvider) goldmark.Extender {
+	return imageAttributes{provider: provider}
+}
+
+type imageAttributes struct {
+	provider ImageProvider
+}
+
+func (receiver imageAttributes) Extend(md goldmark.Markdown) {
+	md.Parser().AddOptions(
+		parser.WithASTTransformers(
+			util.Prioritized(imageAttributesTransformer(receiver), 160),
+		),
+	)
+}
+
+type imageAttributesTransformer struct {
+	provider ImageProvider
+}
+
+func (receiver imageAttributesTransformer) Transform(node *ast.Document, reader text.Reader, parserContext parser.Context) {
+	currentFile, _ := parserContext.Get(LinkResolverContextKey).(string)
+	if currentFile == "" {
+		return
+	}
+
+	_ = ast.Walk(node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		img, ok := n.(*ast.Image)
+		if !ok {
+			return ast.WalkContinue, nil
+		}
+
+		imagePath := ResolveAssetPath(string(img.Destination), currentFile)
+		if imagePath == "" {
+			return ast.WalkContinue, nil
+		}
+
+		assetURL := AssetsPrefix + strings.TrimPrefix(imagePath, "docs/")
+		img.Destination = []byte(assetURL)
+
+		if receiver.provider == nil {
+			return ast.WalkContinue, nil
+		}
+		width, height, found := receiver.provider.Dimensions(imagePath)
+		if !found {
+			return ast.WalkContinue, nil
+		}
+		img.SetAttributeString("width", strconv.Itoa(width))
+		img.SetAttributeString("height", strconv.Itoa(height))
+
+		if widths := receiver.provider.Widths(imagePath); len(widths) > 0 {
+			candidates := make([]string, 0, len(widths)+1)
+			for _, variantWidth := range widths {
+				candidates = append(candidates, fmt.Sprintf("%s?w=%d %dw", assetURL, variantWidth, variantWidth))
+			}
+			candidates = append(candidates, fmt.Sprintf("%s %dw", assetURL, width))
+			img.SetAttributeString("srcset", strings.Join(candidates, ", "))
+		}
+
+		return ast.WalkContinue, nil
+	})
+}
+
+// ResolveAssetPath returns the docs path of a relative (or docs-absolute) image destination,
+// or an empty string if the destination points outside the docs.
+func ResolveAssetPath(destination string, currentFile string) string {
+	if destination == "" || strings.HasPrefix(destination, "//") || strings.HasPrefix(destination, "#") {
+		return ""
+	}
+	parsed, err := url.Parse(destination)
+	if err != nil || parsed.Scheme != "" {
+		return ""
+	}
+	if strings.HasPrefix(parsed.Path, AssetsPrefix) {
+		return ""
+	}
+
+	relativeTo := strings.TrimPrefix(currentFile, "docs/")
+	var target string
+	if strings.HasPrefix(parsed.Path, "/") {
+		target = path.Clean(parsed.Path)
+	} else {
+		target = path.Join(path.Dir(relativeTo), parsed.Path)
+	}
+	target = strings.TrimPrefix(target, "/")
+	if target == "" || target == "." || strings.HasPrefix(target, "../") {
+		return ""
+	}
+
+	return "docs/" + target
+}
@@ -6,14 +6,20 @@ import (
 	"go.abhg.dev/goldmark/frontmatter"
 )
 
-func New() goldmark.Markdown {
+func New(extensions ...goldmark.Extender) goldmark.Markdown {
 	return goldmark.New(
 		goldmark.WithExtensions(
-			extension.GFM,
-			extension.Strikethrough,
-			&frontmatter.Extender{},
-			newLinkResolver(),
-			newSectionSplitter(),
+			append([]goldmark.Extender{
+				extension.GFM,
+				extension.Strikethrough,
+				&frontmatter.Extender{},
+				newLinkResolver(),
+				newContainers(),
+				newConditionals(),
+				newCallouts(),
+				newActions(),
+				newSectionSplitter(),
+			}, extensions...)...,
 		),
 	)
 }
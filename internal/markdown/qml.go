@@ -0,0 +1,158 @@
+package markdown
+
+import (
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// NewQML returns a markdown instance whose renderer emits HTML restricted to the subset supported
+// by Qt's RichText (simple tables, no task list inputs, no nested blockquotes, no code classes).
+// Its parser is identical to the one returned by New, so documents parsed by either can be rendered by it.
+func NewQML(extensions ...goldmark.Extender) goldmark.Markdown {
+	return New(append(extensions, qmlProfile{})...)
+}
+
+type qmlProfile struct{}
+
+func (qmlProfile) Extend(md goldmark.Markdown) {
+	md.Renderer().AddOptions(
+		renderer.WithNodeRenderers(
+			util.Prioritized(qmlRenderer{}, 100),
+		),
+	)
+}
+
+type qmlRenderer struct{}
+
+func (receiver qmlRenderer) RegisterFuncs(registerer renderer.NodeRendererFuncRegisterer) {
+	registerer.Register(ast.KindBlockquote, receiver.renderBlockquote)
+	registerer.Register(KindCallout, receiver.renderCallout)
+	registerer.Register(ast.KindFencedCodeBlock, receiver.renderCodeBlock)
+	registerer.Register(ast.KindCodeBlock, receiver.renderCodeBlock)
+	registerer.Register(east.KindTable, receiver.renderTable)
+	registerer.Register(east.KindTableHeader, receiver.renderTableRow)
+	registerer.Register(east.KindTableRow, receiver.renderTableRow)
+	registerer.Register(east.KindTableCell, receiver.renderTableCell)
+	registerer.Register(east.KindTaskCheckBox, receiver.renderTaskCheckBox)
+	registerer.Register(east.KindStrikethrough, receiver.renderStrikethrough)
+}
+
+func (qmlRenderer) renderBlockquote(writer util.BufWriter, _ []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if isInsideQuote(node) {
+		return ast.WalkContinue, nil
+	}
+
+	if entering {
+		_, _ = writer.WriteString("<blockquote>\n")
+	} else {
+		_, _ = writer.WriteString("</blockquote>\n")
+	}
+	return ast.WalkContinue, nil
+}
+
+func (qmlRenderer) renderCallout(writer util.BufWriter, _ []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if isInsideQuote(node) {
+		return ast.WalkContinue, nil
+	}
+
+	if !entering {
+		_, _ = writer.WriteString("</blockquote>\n")
+		return ast.WalkContinue, nil
+	}
+
+	kind := node.(*Callout).CalloutKind
+	_, _ = writer.WriteString("<blockquote>\n<p><b>" + strings.ToUpper(kind[:1]) + kind[1:] + "</b></p>\n")
+	return ast.WalkContinue, nil
+}
+
+// isInsideQuote reports whether the node is nested in a blockquote or a callout (rendered as a blockquote),
+// Qt renders nested blockquotes unpredictably, so only the outermost one is emitted.
+func isInsideQuote(node ast.Node) bool {
+	for parent := node.Parent(); parent != nil; parent = parent.Parent() {
+		if parent.Kind() == ast.KindBlockquote || parent.Kind() == KindCallout {
+			return true
+		}
+	}
+	return false
+}
+
+func (qmlRenderer) renderCodeBlock(writer util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	_, _ = writer.WriteString("<pre>")
+	lines := node.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		segment := lines.At(i)
+		_, _ = writer.Write(util.EscapeHTML(segment.Value(source)))
+	}
+	_, _ = writer.WriteString("</pre>\n")
+
+	return ast.WalkSkipChildren, nil
+}
+
+func (qmlRenderer) renderTable(writer util.BufWriter, _ []byte, _ ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		_, _ = writer.WriteString(`<table border="1" cellspacing="0" cellpadding="4">` + "\n")
+	} else {
+		_, _ = writer.WriteString("</table>\n")
+	}
+	return ast.WalkContinue, nil
+}
+
+func (qmlRenderer) renderTableRow(writer util.BufWriter, _ []byte, _ ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		_, _ = writer.WriteString("<tr>\n")
+	} else {
+		_, _ = writer.WriteString("</tr>\n")
+	}
+	return ast.WalkContinue, nil
+}
+
+func (qmlRenderer) renderTableCell(writer util.BufWriter, _ []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	tag := "td"
+	if node.Parent().Kind() == east.KindTableHeader {
+		tag = "th"
+	}
+
+	if !entering {
+		_, _ = writer.WriteString("</" + tag + ">\n")
+		return ast.WalkContinue, nil
+	}
+
+	_, _ = writer.WriteString("<" + tag)
+	if cell, ok := node.(*east.TableCell); ok && cell.Alignment != east.AlignNone {
+		_, _ = writer.WriteString(` align="` + cell.Alignment.String() + `"`)
+	}
+	_ = writer.WriteByte('>')
+
+	return ast.WalkContinue, nil
+}
+
+func (qmlRenderer) renderTaskCheckBox(writer util.BufWriter, _ []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	if node.(*east.TaskCheckBox).IsChecked {
+		_, _ = writer.WriteString("&#9745; ")
+	} else {
+		_, _ = writer.WriteString("&#9744; ")
+	}
+	return ast.WalkContinue, nil
+}
+
+func (qmlRenderer) renderStrikethrough(writer util.BufWriter, _ []byte, _ ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		_, _ = writer.WriteString("<s>")
+	} else {
+		_, _ = writer.WriteString("</s>")
+	}
+	return ast.WalkContinue, nil
+}
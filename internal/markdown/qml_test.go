@@ -0,0 +1,76 @@
+package markdown
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+type testImages struct{}
+
+func (testImages) Dimensions(imagePath string) (int, int, bool) {
+	if imagePath != "docs/en/apps/settings.png" {
+		return 0, 0, false
+	}
+	return 1280, 720, true
+}
+
+func (testImages) Widths(string) []int {
+	return []int{480, 960}
+}
+
+// TestQMLGolden renders every testdata/*.md file using the QML profile and compares the output with
+// the .qml file next to it, run with -update to rewrite the golden files.
+func TestQMLGolden(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no test files found")
+	}
+
+	md := NewQML(NewImageAttributes(testImages{}))
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".md")
+		t.Run(name, func(t *testing.T) {
+			source, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			parserContext := parser.NewContext()
+			parserContext.Set(LinkResolverContextKey, "docs/en/apps/"+name+".md")
+			document := md.Parser().Parse(text.NewReader(source), parser.WithContext(parserContext))
+
+			var actual bytes.Buffer
+			if err := md.Renderer().Render(&actual, source, document); err != nil {
+				t.Fatal(err)
+			}
+
+			golden := strings.TrimSuffix(file, ".md") + ".qml"
+			if *update {
+				if err := os.WriteFile(golden, actual.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			expected, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual.String() != string(expected) {
+				t.Errorf("rendered QML differs from %s\n--- expected\n%s\n--- actual\n%s", golden, expected, actual.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,2 @@
+<p>Open the settings using <a class="action" data-action="settings" href="action://settings">Open settings</a> or <a class="action" data-action="settings" href="action://settings">the link</a>.</p>
+<p>See also <a href="document:///en/index.md">the other page</a>.</p>
@@ -0,0 +1,15 @@
+<p>A line ending in a backslash<br>
+continues on the next line.</p>
+<p>A line ending in two spaces<br>
+continues too.</p>
+<p>A soft line break
+stays a single line.</p>
+<p>Raw <!-- raw HTML omitted --> tags are omitted like any raw HTML.</p>
+<ul>
+<li>List item<br>
+with a break</li>
+</ul>
+<blockquote>
+<p>Quoted<br>
+with a break</p>
+</blockquote>
@@ -0,0 +1,17 @@
+<blockquote>
+<p><b>Warning</b></p>
+<p>Flashing a new image deletes all your data.</p>
+</blockquote>
+<blockquote>
+<p><b>Tip</b></p>
+<p>Keep a backup.</p>
+</blockquote>
+<blockquote>
+<p><b>Note</b></p>
+<p>A note spanning</p>
+<p>two paragraphs.</p>
+</blockquote>
+<blockquote>
+<p>A quote</p>
+<p>with a nested quote</p>
+</blockquote>
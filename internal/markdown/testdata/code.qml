@@ -0,0 +1,6 @@
+<pre>devel-su
+pkcon refresh
+</pre>
+<pre>indented &lt;code&gt;
+</pre>
+<p>Inline <code>code</code> too.</p>
@@ -0,0 +1,4 @@
+<h1>Installing apps</h1>
+<p>Some <em>emphasized</em> and <strong>strong</strong> text with <s>removed</s> words.</p>
+<h2>From the store</h2>
+<h3>Details</h3>
@@ -0,0 +1,3 @@
+<p><img src="/assets/en/apps/settings.png" alt="Settings" width="1280" height="720" srcset="/assets/en/apps/settings.png?w=480 480w, /assets/en/apps/settings.png?w=960 960w, /assets/en/apps/settings.png 1280w"></p>
+<p><img src="/assets/en/apps/missing.png" alt="Unknown"></p>
+<p><img src="https://example.com/image.png" alt="Remote"></p>
@@ -0,0 +1,28 @@
+<ul>
+<li>first</li>
+<li>second
+<ul>
+<li>nested</li>
+</ul>
+</li>
+<li>third</li>
+</ul>
+<ol>
+<li>one</li>
+<li>two</li>
+</ol>
+<ul>
+<li>&#9744; open task</li>
+<li>&#9745; done task</li>
+</ul>
+<blockquote>
+<p>Before updating:</p>
+<ul>
+<li>&#9745; back up the data</li>
+<li>&#9744; charge the phone
+<ul>
+<li>&#9744; nested task</li>
+</ul>
+</li>
+</ul>
+</blockquote>
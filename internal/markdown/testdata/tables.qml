@@ -0,0 +1,34 @@
+<table border="1" cellspacing="0" cellpadding="4">
+<tr>
+<th>Default</th>
+<th align="left">Left</th>
+<th align="center">Centered</th>
+<th align="right">Right</th>
+</tr>
+<tr>
+<td>a</td>
+<td align="left">b</td>
+<td align="center">c</td>
+<td align="right">1</td>
+</tr>
+<tr>
+<td>longer text</td>
+<td align="left"><code>code</code></td>
+<td align="center"><strong>bold</strong></td>
+<td align="right">22</td>
+</tr>
+</table>
+<table border="1" cellspacing="0" cellpadding="4">
+<tr>
+<th><strong>Setting</strong></th>
+<th><code>Value</code></th>
+</tr>
+<tr>
+<td>Escaped | pipe</td>
+<td></td>
+</tr>
+<tr>
+<td><em>emphasis</em> and <s>strike</s></td>
+<td><a href="document:///en/apps/other.md">link</a></td>
+</tr>
+</table>
@@ -0,0 +1,33 @@
+package metrics
+
+import "SfosBeginnerGuide/internal/cache"
+
+var cacheRequests = Default.NewCounter(
+	"cache_requests_total",
+	"Number of cache lookups by cache and result (hit or miss).",
+	"cache", "result",
+)
+
+// InstrumentCache counts the hits and misses of the store under the given cache name.
+func InstrumentCache[T any](name string, store cache.Store[T]) cache.Store[T] {
+	return &instrumentedStore[T]{name: name, store: store}
+}
+
+type instrumentedStore[T any] struct {
+	name  string
+	store cache.Store[T]
+}
+
+func (receiver *instrumentedStore[T]) Get(key string) (T, bool) {
+	value, ok := receiver.store.Get(key)
+	if ok {
+		cacheRequests.Inc(receiver.name, "hit")
+	} else {
+		cacheRequests.Inc(receiver.name, "miss")
+	}
+	return value, ok
+}
+
+func (receiver *instrumentedStore[T]) Set(key string, value T) {
+	receiver.store.Set(key, value)
+}
@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"SfosBeginnerGuide/internal/httpx"
+)
+
+var (
+	httpRequests = Default.NewCounter(
+		"http_requests_total",
+		"Number of HTTP requests by route, method and status.",
+		"route", "method", "status",
+	)
+	httpDuration = Default.NewHistogram(
+		"http_request_duration_seconds",
+		"Duration of HTTP requests by route and status.",
+		DefaultBuckets,
+		"route", "status",
+	)
+)
+
+func Handler() http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writer.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		writer.WriteHeader(http.StatusOK)
+		Default.Write(writer)
+	})
+}
+
+// Middleware records the requests handled by the mux, it has to wrap the mux directly because the route
+// is the pattern the mux matched, which keeps the number of label values bounded.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		start := time.Now()
+		recorder := httpx.NewResponseRecorder(writer)
+
+		next.ServeHTTP(recorder, request)
+
+		route := request.Pattern
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(recorder.Status)
+		httpRequests.Inc(route, request.Method, status)
+		httpDuration.Observe(time.Since(start).Seconds(), route, status)
+	})
+}
@@ -0,0 +1,211 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the histogram buckets in seconds used for latencies.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// Default is the registry exposed on the /metrics endpoint.
+var Default = NewRegistry()
+
+type metric interface {
+	write(writer io.Writer)
+}
+
+// Registry is a minimal implementation of the Prometheus text exposition format, it only supports
+// counters and histograms because nothing else is needed.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (receiver *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	counter := &Counter{
+		desc:   desc{name: name, help: help, labels: labels},
+		values: make(map[string]*counterValue),
+	}
+	receiver.register(counter)
+	return counter
+}
+
+func (receiver *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	histogram := &Histogram{
+		desc:    desc{name: name, help: help, labels: labels},
+		buckets: buckets,
+		values:  make(map[string]*histogramValue),
+	}
+	receiver.register(histogram)
+	return histogram
+}
+
+func (receiver *Registry) register(metric metric) {
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	receiver.metrics = append(receiver.metrics, metric)
+}
+
+func (receiver *Registry) Write(writer io.Writer) {
+	receiver.mu.Lock()
+	metrics := append([]metric(nil), receiver.metrics...)
+	receiver.mu.Unlock()
+
+	for _, metric := range metrics {
+		metric.write(writer)
+	}
+}
+
+type desc struct {
+	name   string
+	help   string
+	labels []string
+}
+
+func (receiver desc) key(labelValues []string) string {
+	if len(labelValues) != len(receiver.labels) {
+		panic(fmt.Sprintf("metric %s expects %d label values, got %d", receiver.name, len(receiver.labels), len(labelValues)))
+	}
+	return strings.Join(labelValues, "\xff")
+}
+
+func (receiver desc) header(writer io.Writer, kind string) {
+	_, _ = fmt.Fprintf(writer, "# HELP %s %s\n# TYPE %s %s\n", receiver.name, receiver.help, receiver.name, kind)
+}
+
+func (receiver desc) labelPairs(labelValues []string, extra ...string) string {
+	pairs := make([]string, 0, len(labelValues)+len(extra)/2)
+	for i, value := range labelValues {
+		pairs = append(pairs, receiver.labels[i]+"="+strconv.Quote(value))
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, extra[i]+"="+strconv.Quote(extra[i+1]))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+type Counter struct {
+	desc
+
+	mu     sync.Mutex
+	values map[string]*counterValue
+}
+
+type counterValue struct {
+	labels []string
+	value  float64
+}
+
+func (receiver *Counter) Inc(labelValues ...string) {
+	receiver.Add(1, labelValues...)
+}
+
+func (receiver *Counter) Add(value float64, labelValues ...string) {
+	key := receiver.key(labelValues)
+
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+
+	current, ok := receiver.values[key]
+	if !ok {
+		current = &counterValue{labels: append([]string(nil), labelValues...)}
+		receiver.values[key] = current
+	}
+	current.value += value
+}
+
+func (receiver *Counter) write(writer io.Writer) {
+	receiver.header(writer, "counter")
+
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+
+	for _, key := range sortedKeys(receiver.values) {
+		value := receiver.values[key]
+		_, _ = fmt.Fprintf(writer, "%s%s %s\n", receiver.name, receiver.labelPairs(value.labels), formatFloat(value.value))
+	}
+}
+
+type Histogram struct {
+	desc
+	buckets []float64
+
+	mu     sync.Mutex
+	values map[string]*histogramValue
+}
+
+type histogramValue struct {
+	labels []string
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func (receiver *Histogram) Observe(value float64, labelValues ...string) {
+	key := receiver.key(labelValues)
+
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+
+	current, ok := receiver.values[key]
+	if !ok {
+		current = &histogramValue{
+			labels: append([]string(nil), labelValues...),
+			counts: make([]uint64, len(receiver.buckets)),
+		}
+		receiver.values[key] = current
+	}
+	for i, bound := range receiver.buckets {
+		if value <= bound {
+			current.counts[i]++
+		}
+	}
+	current.count++
+	current.sum += value
+}
+
+func (receiver *Histogram) write(writer io.Writer) {
+	receiver.header(writer, "histogram")
+
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+
+	for _, key := range sortedKeys(receiver.values) {
+		value := receiver.values[key]
+		for i, bound := range receiver.buckets {
+			_, _ = fmt.Fprintf(writer, "%s_bucket%s %d\n", receiver.name, receiver.labelPairs(value.labels, "le", formatFloat(bound)), value.counts[i])
+		}
+		_, _ = fmt.Fprintf(writer, "%s_bucket%s %d\n", receiver.name, receiver.labelPairs(value.labels, "le", "+Inf"), value.count)
+		_, _ = fmt.Fprintf(writer, "%s_sum%s %s\n", receiver.name, receiver.labelPairs(value.labels), formatFloat(value.sum))
+		_, _ = fmt.Fprintf(writer, "%s_count%s %d\n", receiver.name, receiver.labelPairs(value.labels), value.count)
+	}
+}
+
+func sortedKeys[T any](values map[string]T) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatFloat(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,198 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"SfosBeginnerGuide/internal/content"
+)
+
+type Request struct {
+	Language string
+	Query    string
+	Limit    int
+	Filters  Filters
+	// Sort is one of relevance (the default), title or path
+	Sort string
+	// Cursor is the next token of the previous page
+	Cursor string
+}
+
+// Filters narrow the results down, all the given conditions have to match.
+type Filters struct {
+	// PathPrefix limits the results to documents under the path, like `apps/`.
+	PathPrefix string `json:"pathPrefix,omitempty"`
+	// Tags limits the results to documents having all the tags in their front matter.
+	Tags []string `json:"tags,omitempty"`
+	// HasActions limits the results to documents with (or without) actions.
+	HasActions *bool `json:"hasActions,omitempty"`
+	// Difficulty limits the results to documents of the difficulty level.
+	Difficulty string `json:"difficulty,omitempty"`
+}
+
+func (receiver Filters) needsMetadata() bool {
+	return len(receiver.Tags) > 0 || receiver.HasActions != nil || receiver.Difficulty != ""
+}
+
+type Response struct {
+	Results []Result `json:"results"`
+	Facets  Facets   `json:"facets"`
+	// Total is the number of relevant documents matching the filters
+	Total int `json:"total"`
+	// Next is the cursor of the following page, empty on the last one
+	Next string `json:"next,omitempty"`
+	// Suggestion is the query with misspelled words corrected
+	Suggestion string `json:"suggestion,omitempty"`
+	// Corrected tells that the query found nothing and the results are for the suggestion
+	Corrected bool `json:"corrected,omitempty"`
+}
+
+// Stage tells streaming clients whether more refined results will follow.
+type Stage string
+
+const (
+	StageInitial Stage = "initial"
+	StageFinal   Stage = "final"
+)
+
+// EmitFunc receives the results of a streamed search, an error stops the search.
+type EmitFunc func(stage Stage, response *Response) error
+
+type Facets struct {
+	Directories []FacetValue `json:"directories"`
+}
+
+type FacetValue struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+type DocumentMeta struct {
+	Title       string
+	Description string
+	Keywords    []string
+	Tags        []string
+	Difficulty  string
+	HasActions  bool
+}
+
+// terms returns the text of the metadata which is searchable by the lexical search.
+func (receiver *DocumentMeta) terms() string {
+	parts := append([]string{receiver.Title, receiver.Description}, receiver.Keywords...)
+	return strings.Join(append(parts, receiver.Tags...), " ")
+}
+
+// MetadataProvider looks up the front matter of the indexed documents for the metadata filters.
+type MetadataProvider interface {
+	DocumentMeta(ctx context.Context, language, source string) (*DocumentMeta, error)
+}
+
+// ParserMetadata reads the metadata using the content parser, which caches the parsed documents.
+type ParserMetadata struct {
+	Parser content.Parser
+}
+
+func (receiver ParserMetadata) DocumentMeta(ctx context.Context, language, source string) (*DocumentMeta, error) {
+	item, err := receiver.Parser.ParseByPath(ctx, language+"/"+source, content.Options{Format: content.FormatHTML})
+	if err != nil {
+		return nil, err
+	}
+	return &DocumentMeta{
+		Title:       item.Meta.Title,
+		Description: item.Meta.Description,
+		Keywords:    item.Meta.Keywords,
+		Tags:        item.Meta.Tags,
+		Difficulty:  item.Meta.Difficulty,
+		HasActions:  len(item.Meta.Actions) > 0,
+	}, nil
+}
+
+func (receiver *Service) filterByMetadata(ctx context.Context, language string, candidates []scoredChunk, filters Filters) ([]scoredChunk, error) {
+	if !filters.needsMetadata() {
+		return candidates, nil
+	}
+	if receiver.Metadata == nil {
+		return nil, fmt.Errorf("metadata filters are not supported without a metadata provider")
+	}
+
+	matches := make(map[string]bool)
+	result := candidates[:0:0]
+	for _, candidate := range candidates {
+		source := candidate.chunk.source
+		matched, ok := matches[source]
+		if !ok {
+			meta, err := receiver.Metadata.DocumentMeta(ctx, language, source)
+			if err != nil {
+				return nil, fmt.Errorf("failed reading metadata of %s: %w", source, err)
+			}
+			matched = matchesMetadata(meta, filters)
+			matches[source] = matched
+		}
+		if matched {
+			result = append(result, candidate)
+		}
+	}
+	return result, nil
+}
+
+func matchesMetadata(meta *DocumentMeta, filters Filters) bool {
+	if filters.HasActions != nil && meta.HasActions != *filters.HasActions {
+		return false
+	}
+	if filters.Difficulty != "" && meta.Difficulty != filters.Difficulty {
+		return false
+	}
+	for _, tag := range filters.Tags {
+		if !slices.Contains(meta.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// filterByPath keeps the documents under the directory, a prefix without a trailing slash (like a facet value)
+// is a directory too, so that `apps` matches neither `apps-foo/` nor `appsbar.md`.
+func filterByPath(candidates []scoredChunk, prefix string) []scoredChunk {
+	prefix = strings.TrimPrefix(prefix, "/")
+	if prefix == "" {
+		return candidates
+	}
+
+	directory := strings.TrimSuffix(prefix, "/")
+	result := candidates[:0:0]
+	for _, candidate := range candidates {
+		source := candidate.chunk.source
+		if source == directory || strings.HasPrefix(source, directory+"/") {
+			result = append(result, candidate)
+		}
+	}
+	return result
+}
+
+// directoryFacets counts the relevant documents per top-level directory, so that the client can offer
+// narrowing the search down. Documents in the root of the language are not counted.
+func directoryFacets(candidates []scoredChunk) []FacetValue {
+	counts := make(map[string]int)
+	for _, candidate := range candidates {
+		directory, _, found := strings.Cut(candidate.chunk.source, "/")
+		if !found {
+			continue
+		}
+		counts[directory]++
+	}
+
+	result := make([]FacetValue, 0, len(counts))
+	for directory, count := range counts {
+		result = append(result, FacetValue{Value: directory, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Value < result[j].Value
+	})
+	return result
+}
@@ -0,0 +1,33 @@
+package search
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFilterByPath(t *testing.T) {
+	var candidates []scoredChunk
+	for _, source := range []string{"index.md", "apps.md", "apps/index.md", "apps/store/jolla.md", "apps-old/index.md", "appsbar.md"} {
+		candidates = append(candidates, scoredChunk{chunk: &indexedChunk{source: source}})
+	}
+
+	for _, current := range []struct {
+		prefix   string
+		expected []string
+	}{
+		{"", []string{"index.md", "apps.md", "apps/index.md", "apps/store/jolla.md", "apps-old/index.md", "appsbar.md"}},
+		{"apps", []string{"apps/index.md", "apps/store/jolla.md"}},
+		{"apps/", []string{"apps/index.md", "apps/store/jolla.md"}},
+		{"/apps/store", []string{"apps/store/jolla.md"}},
+		{"apps.md", []string{"apps.md"}},
+		{"app", nil},
+	} {
+		var sources []string
+		for _, candidate := range filterByPath(candidates, current.prefix) {
+			sources = append(sources, candidate.chunk.source)
+		}
+		if !slices.Equal(sources, current.expected) {
+			t.Errorf("filterByPath(%q) = %v, expected %v", current.prefix, sources, current.expected)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package search
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+type fileEmbeddings struct {
+	Source string           `json:"source"`
+	Model  string           `json:"model"`
+	Dim    int              `json:"dim"`
+	Chunks []chunkEmbedding `json:"chunks"`
+}
+
+type chunkEmbedding struct {
+	Index  int       `json:"index"`
+	Text   string    `json:"text"`
+	Vector []float32 `json:"vector"`
+}
+
+type indexedChunk struct {
+	source string
+	text   string
+	vector []float32
+	norm   float32
+	terms  map[string]int
+}
+
+type scoredChunk struct {
+	chunk *indexedChunk
+	score float32
+	// reranked tells that the score is the cross-encoder one
+	reranked bool
+}
+
+// Index holds the precomputed embeddings of a single language in memory, so that searching doesn't have
+// to read and decode every embeddings file on each request.
+type Index struct {
+	chunks []*indexedChunk
+	// documentTerms are the terms of the documents' metadata, they match every chunk of the document
+	documentTerms map[string]map[string]int
+	// vocabulary holds the frequency of every known term, misspelled words are corrected to these
+	vocabulary map[string]int
+	// vocabularyByLength groups the vocabulary by the number of letters of the words
+	vocabularyByLength map[int][]string
+	synonyms           *Synonyms
+}
+
+func LoadIndex(root fs.FS) (*Index, error) {
+	index := &Index{}
+	err := fs.WalkDir(root, ".", func(path string, entry fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		if filepath.Ext(path) != ".json" {
+			return nil
+		}
+
+		data, err := fs.ReadFile(root, path)
+		if err != nil {
+			return err
+		}
+
+		var file fileEmbeddings
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil
+		}
+
+		source := trimDocsLangPrefix(file.Source)
+		for _, chunk := range file.Chunks {
+			index.chunks = append(index.chunks, &indexedChunk{
+				source: source,
+				text:   chunk.Text,
+				vector: chunk.Vector,
+				norm:   vectorNorm(chunk.Vector),
+				terms:  termFrequencies(chunk.Text),
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return index, nil
+}
+
+func (receiver *Index) Len() int {
+	return len(receiver.chunks)
+}
+
+func (receiver *Index) sources() []string {
+	var result []string
+	seen := make(map[string]struct{})
+	for _, chunk := range receiver.chunks {
+		if _, ok := seen[chunk.source]; ok {
+			continue
+		}
+		seen[chunk.source] = struct{}{}
+		result = append(result, chunk.source)
+	}
+	return result
+}
+
+func (receiver *Index) addDocumentTerms(source string, text string) {
+	if receiver.documentTerms == nil {
+		receiver.documentTerms = make(map[string]map[string]int)
+	}
+	receiver.documentTerms[source] = termFrequencies(text)
+}
+
+func (receiver *Index) searchVector(query []float32) ([]scoredChunk, error) {
+	if len(query) == 0 {
+		return nil, errors.New("empty query vector")
+	}
+
+	queryNorm := vectorNorm(query)
+	if queryNorm == 0 {
+		return nil, errors.New("zero query vector norm")
+	}
+
+	results := newDocumentCandidates()
+	for _, chunk := range receiver.chunks {
+		results.add(scoredChunk{
+			chunk: chunk,
+			score: cosineSimilarity(query, queryNorm, chunk.vector, chunk.norm),
+		})
+	}
+	return results.list, nil
+}
+
+// searchLexical scores the chunks by the share of the query's words they contain, it's used when
+// the embeddings server is not available. Synonyms count for the words they replace, so that they find
+// more documents without lowering the score of the ones containing the words as written.
+func (receiver *Index) searchLexical(query string) []scoredChunk {
+	concepts := receiver.synonyms.concepts(tokenize(query))
+	if len(concepts) == 0 {
+		return nil
+	}
+
+	results := newDocumentCandidates()
+	for _, chunk := range receiver.chunks {
+		documentTerms := receiver.documentTerms[chunk.source]
+		contains := func(term string) bool {
+			return chunk.terms[term] > 0 || documentTerms[term] > 0
+		}
+		matched := 0
+		for _, concept := range concepts {
+			if concept.matches(contains) {
+				matched++
+			}
+		}
+		if matched == 0 {
+			continue
+		}
+		results.add(scoredChunk{
+			chunk: chunk,
+			score: float32(matched) / float32(len(concepts)),
+		})
+	}
+	return results.list
+}
+
+// documentCandidates keeps only the best scoring chunk of every document while scanning the index.
+type documentCandidates struct {
+	list     []scoredChunk
+	bySource map[string]int
+}
+
+func newDocumentCandidates() *documentCandidates {
+	return &documentCandidates{bySource: make(map[string]int)}
+}
+
+func (receiver *documentCandidates) add(candidate scoredChunk) {
+	if i, ok := receiver.bySource[candidate.chunk.source]; ok {
+		if candidate.score > receiver.list[i].score {
+			receiver.list[i] = candidate
+		}
+		return
+	}
+	receiver.bySource[candidate.chunk.source] = len(receiver.list)
+	receiver.list = append(receiver.list, candidate)
+}
+
+func termFrequencies(text string) map[string]int {
+	result := make(map[string]int)
+	for _, term := range tokenize(text) {
+		result[term]++
+	}
+	return result
+}
+
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !isWordRune(r)
+	})
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,22 @@
+package search
+
+import "SfosBeginnerGuide/internal/metrics"
+
+var (
+	searchRequests = metrics.Default.NewCounter(
+		"search_requests_total",
+		"Number of searches by language and mode (semantic or lexical).",
+		"language", "mode",
+	)
+	upstreamDuration = metrics.Default.NewHistogram(
+		"search_upstream_duration_seconds",
+		"Duration of requests to the embeddings server by operation (embed, rerank or health).",
+		metrics.DefaultBuckets,
+		"operation",
+	)
+	upstreamErrors = metrics.Default.NewCounter(
+		"search_upstream_errors_total",
+		"Number of failed requests to the embeddings server by operation.",
+		"operation",
+	)
+)
@@ -0,0 +1,148 @@
+package search
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+)
+
+const (
+	SortRelevance = "relevance"
+	SortTitle     = "title"
+	SortPath      = "path"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ranking is the complete ordered result of a search. It's cached so that the following pages usually
+// neither embed nor rerank again, the cursors don't depend on it though.
+type ranking struct {
+	results    []*sortableResult
+	facets     Facets
+	suggestion string
+	corrected  bool
+	topScore   float32
+}
+
+// rankingKey identifies the search a ranking belongs to, the limit doesn't change the ranking.
+func rankingKey(request Request, sortBy string) string {
+	data, _ := json.Marshal([]any{request.Language, request.Query, request.Filters, sortBy})
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:12])
+}
+
+// cursor is the position of the last result of a page, the clients get it as an opaque token. The following
+// page starts after the position, so a ranking computed again when the cached one is gone neither skips
+// nor repeats documents.
+type cursor struct {
+	Key      string  `json:"k"`
+	Sort     string  `json:"o"`
+	Reranked bool    `json:"r,omitempty"`
+	Score    float32 `json:"s,omitempty"`
+	Title    string  `json:"t,omitempty"`
+	Source   string  `json:"p"`
+}
+
+func (receiver *cursor) encode() string {
+	data, _ := json.Marshal(receiver)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor reads the cursor, it has to belong to a search with the same query, filters and sort.
+func decodeCursor(token string, key string, sortBy string) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var result cursor
+	if err := json.Unmarshal(data, &result); err != nil || result.Source == "" {
+		return nil, ErrInvalidCursor
+	}
+	if result.Key != key || result.Sort != sortBy {
+		return nil, ErrInvalidCursor
+	}
+	return &result, nil
+}
+
+// sortableResult is a result together with what it can be sorted by.
+type sortableResult struct {
+	Result
+	title string
+	// reranked results come before the ones which were only scored by cosine similarity
+	reranked bool
+}
+
+func (receiver *sortableResult) cursor(key string, sortBy string) *cursor {
+	position := &cursor{Key: key, Sort: sortBy, Source: receiver.Source}
+	switch sortBy {
+	case SortTitle:
+		position.Title = receiver.title
+	case SortPath:
+	default:
+		position.Reranked = receiver.reranked
+		position.Score = receiver.Score
+	}
+	return position
+}
+
+// compareResults orders the results, the source breaks ties so that every position is unique.
+func compareResults(sortBy string) func(a, b *sortableResult) int {
+	switch sortBy {
+	case SortTitle:
+		return func(a, b *sortableResult) int {
+			return cmp.Or(
+				strings.Compare(strings.ToLower(a.title), strings.ToLower(b.title)),
+				strings.Compare(a.Source, b.Source),
+			)
+		}
+	case SortPath:
+		return func(a, b *sortableResult) int {
+			return strings.Compare(a.Source, b.Source)
+		}
+	default:
+		return func(a, b *sortableResult) int {
+			if a.reranked != b.reranked {
+				if a.reranked {
+					return -1
+				}
+				return 1
+			}
+			return cmp.Or(cmp.Compare(b.Score, a.Score), strings.Compare(a.Source, b.Source))
+		}
+	}
+}
+
+func sortResults(results []*sortableResult, sortBy string) {
+	slices.SortFunc(results, compareResults(sortBy))
+}
+
+// paginate returns the page of the ranking after the cursor, next is empty on the last page.
+func (receiver *ranking) paginate(key string, sortBy string, after *cursor, limit int) (page []Result, next string) {
+	compare := compareResults(sortBy)
+	start := 0
+	if after != nil {
+		position := &sortableResult{
+			Result:   Result{Source: after.Source, Score: after.Score},
+			title:    after.Title,
+			reranked: after.Reranked,
+		}
+		start, _ = slices.BinarySearchFunc(receiver.results, position, compare)
+		if start < len(receiver.results) && compare(receiver.results[start], position) == 0 {
+			start++
+		}
+	}
+
+	end := min(start+limit, len(receiver.results))
+	page = make([]Result, 0, end-start)
+	for _, result := range receiver.results[start:end] {
+		page = append(page, result.Result)
+	}
+	if end < len(receiver.results) {
+		next = receiver.results[end-1].cursor(key, sortBy).encode()
+	}
+	return page, next
+}
@@ -0,0 +1,161 @@
+package search
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"SfosBeginnerGuide/internal/cache"
+	"SfosBeginnerGuide/internal/config"
+)
+
+func TestDecodeCursor(t *testing.T) {
+	valid := (&cursor{Key: "key", Sort: SortRelevance, Score: 0.5, Source: "apps/store.md"}).encode()
+	decoded, err := decodeCursor(valid, "key", SortRelevance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Score != 0.5 || decoded.Source != "apps/store.md" {
+		t.Errorf("decoded %+v", decoded)
+	}
+
+	for name, token := range map[string]string{
+		"not base64":     "not base64!",
+		"not json":       base64.RawURLEncoding.EncodeToString([]byte("nope")),
+		"without source": (&cursor{Key: "key", Sort: SortRelevance, Score: 0.5}).encode(),
+		"other search":   (&cursor{Key: "other", Sort: SortRelevance, Source: "index.md"}).encode(),
+		"other sort":     (&cursor{Key: "key", Sort: SortPath, Source: "index.md"}).encode(),
+	} {
+		if _, err := decodeCursor(token, "key", SortRelevance); err != ErrInvalidCursor {
+			t.Errorf("%s: error %v, expected %v", name, err, ErrInvalidCursor)
+		}
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	results := []*sortableResult{
+		{Result: Result{Source: "b.md", Score: 0.5}, title: "Zebra"},
+		{Result: Result{Source: "a.md", Score: 0.5}, title: "apple"},
+		{Result: Result{Source: "c.md", Score: 0.9}, title: "Mango"},
+		{Result: Result{Source: "d.md", Score: 0.1}, title: "mango"},
+		{Result: Result{Source: "e.md", Score: 0.2}, title: "Banana", reranked: true},
+	}
+
+	for sortBy, expected := range map[string][]string{
+		SortRelevance: {"e.md", "c.md", "a.md", "b.md", "d.md"},
+		SortTitle:     {"a.md", "e.md", "c.md", "d.md", "b.md"},
+		SortPath:      {"a.md", "b.md", "c.md", "d.md", "e.md"},
+	} {
+		ranked := &ranking{results: slices.Clone(results)}
+		sortResults(ranked.results, sortBy)
+
+		var sources []string
+		var after *cursor
+		for pages := 0; ; pages++ {
+			if pages > len(results) {
+				t.Fatalf("%s: paging doesn't end", sortBy)
+			}
+			page, next := ranked.paginate("key", sortBy, after, 2)
+			for _, result := range page {
+				sources = append(sources, result.Source)
+			}
+			if next == "" {
+				break
+			}
+			var err error
+			if after, err = decodeCursor(next, "key", sortBy); err != nil {
+				t.Fatalf("%s: %v", sortBy, err)
+			}
+		}
+		if !slices.Equal(sources, expected) {
+			t.Errorf("%s: pages %v, expected %v", sortBy, sources, expected)
+		}
+	}
+}
+
+func TestPaginateChangedRanking(t *testing.T) {
+	first := &ranking{results: []*sortableResult{
+		{Result: Result{Source: "a.md", Score: 0.9}},
+		{Result: Result{Source: "b.md", Score: 0.8}},
+		{Result: Result{Source: "c.md", Score: 0.7}},
+		{Result: Result{Source: "d.md", Score: 0.6}},
+	}}
+	_, next := first.paginate("key", SortRelevance, nil, 2)
+	after, err := decodeCursor(next, "key", SortRelevance)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the last result of the first page is gone and a new one was added before it, the page still starts after it
+	second := &ranking{results: []*sortableResult{
+		{Result: Result{Source: "a.md", Score: 0.9}},
+		{Result: Result{Source: "new.md", Score: 0.85}},
+		{Result: Result{Source: "c.md", Score: 0.7}},
+		{Result: Result{Source: "d.md", Score: 0.6}},
+	}}
+	page, next := second.paginate("key", SortRelevance, after, 2)
+	if sources := resultSources(page); !slices.Equal(sources, []string{"c.md", "d.md"}) || next != "" {
+		t.Errorf("page %v with next %q, expected [c.md d.md] without next", sources, next)
+	}
+}
+
+func TestSearchPagesAfterRankingEvicted(t *testing.T) {
+	docs := fstest.MapFS{}
+	var expected []string
+	for i := range 7 {
+		source := fmt.Sprintf("apps/page%d.md", i)
+		text := "sailfish"
+		if i%3 == 0 {
+			text += " phone"
+		}
+		docs[fmt.Sprintf("docs/en/page%d.json", i)] = &fstest.MapFile{Data: []byte(fmt.Sprintf(
+			`{"source": "docs/en/%s", "chunks": [{"index": 0, "text": %q}]}`, source, text,
+		))}
+		expected = append(expected, source)
+	}
+
+	cfg := config.Default().Search
+	cfg.Enabled = true
+	cfg.LexicalFallback = true
+	service := NewService(docs, cfg)
+
+	for _, sortBy := range []string{SortRelevance, SortPath} {
+		request := Request{Language: "en", Query: "sailfish phone", Limit: 3, Sort: sortBy}
+		var sources []string
+		for pages := 0; ; pages++ {
+			if pages > len(expected) {
+				t.Fatalf("%s: paging doesn't end", sortBy)
+			}
+			response, err := service.Search(context.Background(), request)
+			if err != nil {
+				t.Fatalf("%s: %v", sortBy, err)
+			}
+			if response.Total != len(expected) {
+				t.Errorf("%s: total %d, expected %d", sortBy, response.Total, len(expected))
+			}
+			sources = append(sources, resultSources(response.Results)...)
+			if response.Next == "" {
+				break
+			}
+			request.Cursor = response.Next
+			// the cached ranking is gone before the next page, like after a restart or on another instance
+			service.rankings = cache.NewLRU[*ranking](rankingCacheSize, rankingCacheTTL)
+		}
+
+		slices.Sort(sources)
+		if !slices.Equal(sources, expected) {
+			t.Errorf("%s: paged through %v, expected every document once %v", sortBy, sources, expected)
+		}
+	}
+}
+
+func resultSources(results []Result) []string {
+	sources := make([]string, 0, len(results))
+	for _, result := range results {
+		sources = append(sources, result.Source)
+	}
+	return sources
+}
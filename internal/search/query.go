@@ -0,0 +1,315 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	synonymsFile = "synonyms.yaml"
+	// minCorrectedLength keeps short words and abbreviations from being "corrected" to other ones
+	minCorrectedLength = 4
+)
+
+// Synonyms are groups of words and phrases with the same meaning, every phrase is stored as its terms.
+type Synonyms struct {
+	groups [][][]string
+}
+
+// LoadSynonyms reads the synonyms of a language, a missing file means no synonyms.
+func LoadSynonyms(root fs.FS, name string) (*Synonyms, error) {
+	data, err := fs.ReadFile(root, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Synonyms{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read synonyms %s: %w", name, err)
+	}
+
+	var file struct {
+		Synonyms [][]string `yaml:"synonyms"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse synonyms %s: %w", name, err)
+	}
+
+	synonyms := &Synonyms{}
+	for _, group := range file.Synonyms {
+		var phrases [][]string
+		for _, phrase := range group {
+			if terms := tokenize(phrase); len(terms) > 0 {
+				phrases = append(phrases, terms)
+			}
+		}
+		if len(phrases) > 1 {
+			synonyms.groups = append(synonyms.groups, phrases)
+		}
+	}
+	return synonyms, nil
+}
+
+// expand returns the terms of the phrases which mean the same as a phrase of the query.
+func (receiver *Synonyms) expand(terms []string) []string {
+	var result []string
+	for _, group := range receiver.groups {
+		matched := -1
+		for i, phrase := range group {
+			if containsPhrase(terms, phrase) {
+				matched = i
+				break
+			}
+		}
+		if matched < 0 {
+			continue
+		}
+		for i, phrase := range group {
+			if i != matched {
+				result = append(result, phrase...)
+			}
+		}
+	}
+	return result
+}
+
+// queryConcept is a word of the query, it's also found by the phrases which mean the same as a phrase
+// of the query containing the word.
+type queryConcept struct {
+	term         string
+	alternatives [][]string
+}
+
+// matches tells whether the word or all the terms of one of its alternatives are contained.
+func (receiver *queryConcept) matches(contains func(term string) bool) bool {
+	if contains(receiver.term) {
+		return true
+	}
+	return slices.ContainsFunc(receiver.alternatives, func(phrase []string) bool {
+		return !slices.ContainsFunc(phrase, func(term string) bool { return !contains(term) })
+	})
+}
+
+// concepts returns the distinct words of the query with their synonyms.
+func (receiver *Synonyms) concepts(terms []string) []*queryConcept {
+	var result []*queryConcept
+	byTerm := make(map[string]*queryConcept)
+	for _, term := range terms {
+		if _, ok := byTerm[term]; !ok {
+			byTerm[term] = &queryConcept{term: term}
+			result = append(result, byTerm[term])
+		}
+	}
+	if receiver == nil {
+		return result
+	}
+
+	for _, group := range receiver.groups {
+		for i, phrase := range group {
+			if !containsPhrase(terms, phrase) {
+				continue
+			}
+			for _, term := range phrase {
+				for j, alternative := range group {
+					if j != i {
+						byTerm[term].alternatives = append(byTerm[term].alternatives, alternative)
+					}
+				}
+			}
+		}
+	}
+	return result
+}
+
+func containsPhrase(terms []string, phrase []string) bool {
+	for start := 0; start+len(phrase) <= len(terms); start++ {
+		matches := true
+		for i, term := range phrase {
+			if terms[start+i] != term {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+type analyzedQuery struct {
+	// expanded is the query followed by the synonyms of its words
+	expanded string
+	// suggestion is the query with the misspelled words corrected, empty when nothing was corrected
+	suggestion string
+	// expandedSuggestion is the suggestion followed by the synonyms of its words
+	expandedSuggestion string
+}
+
+// prepareQueries builds the vocabulary used for spelling corrections, it has to be called
+// once all the terms of the documents are known.
+func (receiver *Index) prepareQueries(synonyms *Synonyms) {
+	receiver.synonyms = synonyms
+	receiver.vocabulary = make(map[string]int)
+	for _, chunk := range receiver.chunks {
+		for term, count := range chunk.terms {
+			receiver.vocabulary[term] += count
+		}
+	}
+	for _, terms := range receiver.documentTerms {
+		for term, count := range terms {
+			receiver.vocabulary[term] += count
+		}
+	}
+	for _, group := range synonyms.groups {
+		for _, phrase := range group {
+			for _, term := range phrase {
+				receiver.vocabulary[term]++
+			}
+		}
+	}
+
+	receiver.vocabularyByLength = make(map[int][]string)
+	for term := range receiver.vocabulary {
+		length := utf8.RuneCountInString(term)
+		receiver.vocabularyByLength[length] = append(receiver.vocabularyByLength[length], term)
+	}
+}
+
+// analyze expands the query with synonyms and suggests a correction of its misspelled words. The query
+// itself is kept as the user wrote it, the embedding model understands it better than its terms.
+func (receiver *Index) analyze(query string) analyzedQuery {
+	result := analyzedQuery{expanded: receiver.expand(query)}
+
+	var corrected strings.Builder
+	changed := false
+	for _, word := range splitWords(query) {
+		if !word.isWord {
+			corrected.WriteString(word.text)
+			continue
+		}
+		correction, ok := receiver.correct(strings.ToLower(word.text))
+		if !ok {
+			corrected.WriteString(word.text)
+			continue
+		}
+		corrected.WriteString(matchCase(correction, word.text))
+		changed = true
+	}
+	if changed {
+		result.suggestion = corrected.String()
+		result.expandedSuggestion = receiver.expand(result.suggestion)
+	}
+	return result
+}
+
+// expand appends the synonyms of the query's phrases to the query.
+func (receiver *Index) expand(query string) string {
+	if receiver.synonyms == nil {
+		return query
+	}
+	if synonyms := receiver.synonyms.expand(tokenize(query)); len(synonyms) > 0 {
+		return query + " " + strings.Join(synonyms, " ")
+	}
+	return query
+}
+
+type queryPart struct {
+	text   string
+	isWord bool
+}
+
+// splitWords splits the text into the words (as found by tokenize) and what's between them.
+func splitWords(text string) []queryPart {
+	var parts []queryPart
+	for text != "" {
+		first, _ := utf8.DecodeRuneInString(text)
+		isWord := isWordRune(first)
+		end := strings.IndexFunc(text, func(r rune) bool { return isWordRune(r) != isWord })
+		if end < 0 {
+			end = len(text)
+		}
+		parts = append(parts, queryPart{text: text[:end], isWord: isWord})
+		text = text[end:]
+	}
+	return parts
+}
+
+// matchCase writes the correction the way the original word was written: in upper case, capitalized or lower case.
+func matchCase(correction string, original string) string {
+	first, _ := utf8.DecodeRuneInString(original)
+	switch {
+	case strings.ToUpper(original) == original && utf8.RuneCountInString(original) > 1:
+		return strings.ToUpper(correction)
+	case unicode.IsUpper(first):
+		correctionFirst, size := utf8.DecodeRuneInString(correction)
+		return string(unicode.ToUpper(correctionFirst)) + correction[size:]
+	default:
+		return correction
+	}
+}
+
+// correct returns the most frequent word of the vocabulary closest to an unknown term.
+func (receiver *Index) correct(term string) (string, bool) {
+	if _, known := receiver.vocabulary[term]; known || len(receiver.vocabulary) == 0 {
+		return "", false
+	}
+	runes := []rune(term)
+	if len(runes) < minCorrectedLength || strings.IndexFunc(term, unicode.IsDigit) >= 0 {
+		return "", false
+	}
+
+	maxDistance := 1
+	if len(runes) >= 8 {
+		maxDistance = 2
+	}
+
+	best, bestDistance, bestFrequency := "", maxDistance+1, 0
+	// words whose length differs by more than the distance can't be close enough
+	for length := len(runes) - maxDistance; length <= len(runes)+maxDistance; length++ {
+		for _, candidate := range receiver.vocabularyByLength[length] {
+			distance := editDistance(runes, []rune(candidate))
+			if distance > maxDistance {
+				continue
+			}
+			frequency := receiver.vocabulary[candidate]
+			better := distance < bestDistance ||
+				distance == bestDistance && (frequency > bestFrequency || frequency == bestFrequency && candidate < best)
+			if better {
+				best, bestDistance, bestFrequency = candidate, distance, frequency
+			}
+		}
+	}
+	return best, best != ""
+}
+
+// editDistance is the number of inserted, deleted, replaced or swapped neighbouring letters between the words.
+func editDistance(a []rune, b []rune) int {
+	previousRow := make([]int, len(b)+1)
+	row := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range row {
+		row[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(row[j]+1, current[j-1]+1, row[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				current[j] = min(current[j], previousRow[j-2]+1)
+			}
+		}
+		previousRow, row, current = row, current, previousRow
+	}
+	return row[len(b)]
+}
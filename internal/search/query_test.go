@@ -0,0 +1,175 @@
+package search
+
+import (
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+const testSynonyms = `synonyms:
+  - [sailfish, sailfishos, sfos]
+  - [play store, playstore, aurora store]
+  - [file manager, files]
+`
+
+func newTestIndex(t *testing.T, documents map[string]string) *Index {
+	t.Helper()
+	synonyms, err := LoadSynonyms(fstest.MapFS{synonymsFile: {Data: []byte(testSynonyms)}}, synonymsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	index := &Index{}
+	for source, text := range documents {
+		index.chunks = append(index.chunks, &indexedChunk{source: source, text: text, terms: termFrequencies(text)})
+	}
+	index.prepareQueries(synonyms)
+	return index
+}
+
+func TestEditDistance(t *testing.T) {
+	for _, current := range []struct {
+		a, b     string
+		distance int
+	}{
+		{"", "", 0},
+		{"store", "store", 0},
+		{"", "abc", 3},
+		{"sailfsh", "sailfish", 1},
+		{"settingss", "settings", 1},
+		{"stroe", "store", 1},
+		{"setings", "settings", 1},
+		{"kitten", "sitting", 3},
+		{"häuser", "hauser", 1},
+	} {
+		if distance := editDistance([]rune(current.a), []rune(current.b)); distance != current.distance {
+			t.Errorf("editDistance(%q, %q) = %d, expected %d", current.a, current.b, distance, current.distance)
+		}
+		if distance := editDistance([]rune(current.b), []rune(current.a)); distance != current.distance {
+			t.Errorf("editDistance(%q, %q) = %d, expected %d", current.b, current.a, distance, current.distance)
+		}
+	}
+}
+
+func TestCorrect(t *testing.T) {
+	index := newTestIndex(t, map[string]string{
+		"a.md": "Open the settings of the phone, then install the application using the installation wizard.",
+		"b.md": "The settings have a store, the store has apps. Xperia 10 owners use the Jolla store app.",
+		"c.md": "The stone app isn't related.",
+	})
+
+	for _, current := range []struct {
+		term       string
+		correction string
+	}{
+		{"settngs", "settings"},
+		{"sailfsh", "sailfish"},
+		{"instalaton", "installation"},
+		// the more frequent of equally close words
+		{"stowe", "store"},
+		// known words are never corrected
+		{"settings", ""},
+		{"sfos", ""},
+		// too short or containing digits
+		{"aps", ""},
+		{"hav", ""},
+		{"xperia1O", ""},
+		{"10iii", ""},
+		{"5g", ""},
+		// too far from every word
+		{"qwertzuiop", ""},
+	} {
+		correction, ok := index.correct(current.term)
+		if correction != current.correction || ok != (current.correction != "") {
+			t.Errorf("correct(%q) = %q, %v, expected %q", current.term, correction, ok, current.correction)
+		}
+	}
+}
+
+func TestMatchCase(t *testing.T) {
+	for _, current := range []struct {
+		correction, original, expected string
+	}{
+		{"settings", "settngs", "settings"},
+		{"settings", "SETTNGS", "SETTINGS"},
+		{"sailfish", "Sailfsh", "Sailfish"},
+		{"sailfish", "sAilfsh", "sailfish"},
+		{"über", "Ubr", "Über"},
+		{"a", "A", "A"},
+	} {
+		if actual := matchCase(current.correction, current.original); actual != current.expected {
+			t.Errorf("matchCase(%q, %q) = %q, expected %q", current.correction, current.original, actual, current.expected)
+		}
+	}
+}
+
+func TestSplitWords(t *testing.T) {
+	for _, current := range []struct {
+		text     string
+		expected []queryPart
+	}{
+		{"", nil},
+		{"store", []queryPart{{"store", true}}},
+		{"Sailfish SETTNGS?", []queryPart{{"Sailfish", true}, {" ", false}, {"SETTNGS", true}, {"?", false}}},
+		{" f-droid 5G ", []queryPart{{" ", false}, {"f", true}, {"-", false}, {"droid", true}, {" ", false}, {"5G", true}, {" ", false}}},
+		{"Äpfel, Birnen", []queryPart{{"Äpfel", true}, {", ", false}, {"Birnen", true}}},
+	} {
+		if actual := splitWords(current.text); !slices.Equal(actual, current.expected) {
+			t.Errorf("splitWords(%q) = %v, expected %v", current.text, actual, current.expected)
+		}
+	}
+}
+
+func TestSynonymsExpand(t *testing.T) {
+	synonyms := newTestIndex(t, nil).synonyms
+
+	for _, current := range []struct {
+		terms    []string
+		expected []string
+	}{
+		{nil, nil},
+		{[]string{"settings"}, nil},
+		{[]string{"sfos", "settings"}, []string{"sailfish", "sailfishos"}},
+		{[]string{"install", "play", "store"}, []string{"playstore", "aurora", "store"}},
+		// only whole phrases match
+		{[]string{"store", "play"}, nil},
+		{[]string{"files", "sailfish"}, []string{"sailfishos", "sfos", "file", "manager"}},
+	} {
+		if actual := synonyms.expand(current.terms); !slices.Equal(actual, current.expected) {
+			t.Errorf("expand(%v) = %v, expected %v", current.terms, actual, current.expected)
+		}
+	}
+}
+
+func TestSearchLexicalSynonymsDontLowerScores(t *testing.T) {
+	index := newTestIndex(t, map[string]string{
+		"sailfish.md":  "Sailfish is the operating system.",
+		"sfos.md":      "SFOS is short for it.",
+		"playstore.md": "Apps from the Playstore.",
+		"store.md":     "The store has apps.",
+		"unrelated.md": "Nothing to see.",
+	})
+
+	for _, current := range []struct {
+		query    string
+		expected map[string]float32
+	}{
+		{"sailfish", map[string]float32{"sailfish.md": 1, "sfos.md": 1}},
+		{"Sailfish apps", map[string]float32{"sailfish.md": 0.5, "sfos.md": 0.5, "playstore.md": 0.5, "store.md": 0.5}},
+		{"play store", map[string]float32{"playstore.md": 1, "store.md": 0.5}},
+	} {
+		scores := make(map[string]float32)
+		for _, candidate := range index.searchLexical(current.query) {
+			scores[candidate.chunk.source] = candidate.score
+		}
+		if len(scores) != len(current.expected) {
+			t.Errorf("%q: scores %v, expected %v", current.query, scores, current.expected)
+			continue
+		}
+		for source, score := range current.expected {
+			if scores[source] != score {
+				t.Errorf("%q: scores %v, expected %v", current.query, scores, current.expected)
+				break
+			}
+		}
+	}
+}
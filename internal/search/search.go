@@ -7,19 +7,19 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"io/fs"
 	"math"
 	"net/http"
 	"path/filepath"
 	"sort"
 	"strings"
 	"time"
+
+	"SfosBeginnerGuide/internal/httpx"
+	"SfosBeginnerGuide/internal/tracing"
 )
 
 const (
 	defaultTimeout     = 60 * time.Second
-	maxResultsDefault  = 20
-	maxResultsLimit    = 100
 	embeddingQueryMode = "query"
 )
 
@@ -33,101 +33,145 @@ type Client struct {
 	HTTP    *http.Client
 }
 
-func (receiver *Client) EmbedQuery(ctx context.Context, query string) ([]float32, error) {
-	if strings.TrimSpace(receiver.BaseURL) == "" {
-		return nil, errors.New("embeddings server is empty")
-	}
+func (receiver *Client) EmbedQuery(ctx context.Context, query string) (vector []float32, err error) {
+	ctx, span := tracing.StartKind(ctx, "search.EmbedQuery", tracing.KindClient)
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+
 	reqBody := embedRequest{
 		Texts: []string{query},
 		Mode:  embeddingQueryMode,
 	}
-	payload, err := json.Marshal(reqBody)
-	if err != nil {
+
+	var parsed embedResponse
+	if err := receiver.post(ctx, "/embed", reqBody, &parsed); err != nil {
 		return nil, err
 	}
+	if len(parsed.Vectors) != 1 {
+		return nil, fmt.Errorf("unexpected embed response size: %d", len(parsed.Vectors))
+	}
 
-	client := receiver.HTTP
-	if client == nil {
-		client = &http.Client{Timeout: defaultTimeout}
+	vector = make([]float32, len(parsed.Vectors[0]))
+	for i, v := range parsed.Vectors[0] {
+		vector[i] = float32(v)
 	}
+	span.SetAttribute("embedding.dim", len(vector))
+	return vector, nil
+}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(receiver.BaseURL, "/")+"/embed", bytes.NewReader(payload))
-	if err != nil {
-		return nil, err
+// Rerank scores every candidate text against the query using the cross-encoder of the embeddings server,
+// the scores are normalized to 0..1 and returned in the order of the candidates.
+func (receiver *Client) Rerank(ctx context.Context, query string, candidates []string) (scores []float32, err error) {
+	ctx, span := tracing.StartKind(ctx, "search.Rerank", tracing.KindClient)
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+	span.SetAttribute("rerank.candidates", len(candidates))
+
+	reqBody := rerankRequest{
+		Query:      query,
+		Candidates: candidates,
+		Normalize:  true,
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := client.Do(req)
-	if err != nil {
+	var parsed rerankResponse
+	if err := receiver.post(ctx, "/rerank", reqBody, &parsed); err != nil {
 		return nil, err
 	}
-	body, err := io.ReadAll(resp.Body)
-	resp.Body.Close()
+	if len(parsed.Scores) != len(candidates) {
+		return nil, fmt.Errorf("rerank response size mismatch: got %d scores, expected %d", len(parsed.Scores), len(candidates))
+	}
+
+	scores = make([]float32, len(parsed.Scores))
+	for i, score := range parsed.Scores {
+		scores[i] = float32(score)
+	}
+	return scores, nil
+}
+
+func (receiver *Client) Health(ctx context.Context) (*HealthResponse, error) {
+	body, err := receiver.do(ctx, http.MethodGet, "/health", nil)
 	if err != nil {
 		return nil, err
 	}
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("embed status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
-	}
 
-	var parsed embedResponse
+	var parsed HealthResponse
 	if err := json.Unmarshal(body, &parsed); err != nil {
 		return nil, err
 	}
-	if len(parsed.Vectors) != 1 {
-		return nil, fmt.Errorf("unexpected embed response size: %d", len(parsed.Vectors))
+	return &parsed, nil
+}
+
+func (receiver *Client) post(ctx context.Context, endpoint string, payload any, target any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
 	}
 
-	vector := make([]float32, len(parsed.Vectors[0]))
-	for i, v := range parsed.Vectors[0] {
-		vector[i] = float32(v)
+	body, err := receiver.do(ctx, http.MethodPost, endpoint, data)
+	if err != nil {
+		return err
 	}
-	return vector, nil
+
+	return json.Unmarshal(body, target)
 }
 
-func Search(ctx context.Context, root fs.FS, client *Client, query string, limit int) ([]Result, error) {
-	if strings.TrimSpace(query) == "" {
-		return nil, errors.New("query is required")
-	}
-	if limit <= 0 {
-		limit = maxResultsDefault
-	}
-	if limit > maxResultsLimit {
-		limit = maxResultsLimit
+func (receiver *Client) do(ctx context.Context, method string, endpoint string, payload []byte) ([]byte, error) {
+	operation := strings.TrimPrefix(endpoint, "/")
+	start := time.Now()
+
+	body, err := receiver.send(ctx, method, endpoint, payload)
+	upstreamDuration.Observe(time.Since(start).Seconds(), operation)
+	if err != nil {
+		upstreamErrors.Inc(operation)
 	}
 
-	if root == nil {
-		return nil, errors.New("search assets are not configured")
+	return body, err
+}
+
+func (receiver *Client) send(ctx context.Context, method string, endpoint string, payload []byte) ([]byte, error) {
+	if strings.TrimSpace(receiver.BaseURL) == "" {
+		return nil, errors.New("embeddings server is empty")
 	}
+
+	client := receiver.HTTP
 	if client == nil {
-		return nil, errors.New("embeddings client is required")
+		client = &http.Client{Timeout: defaultTimeout}
 	}
 
-	queryVector, err := client.EmbedQuery(ctx, query)
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(receiver.BaseURL, "/")+endpoint, reader)
 	if err != nil {
 		return nil, err
 	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if id := httpx.RequestID(ctx); id != "" {
+		req.Header.Set(httpx.RequestIDHeader, id)
+	}
+	tracing.Inject(ctx, req.Header)
 
-	results, err := searchEmbeddings(root, queryVector)
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
 	}
-
-	sort.Slice(results, func(i, j int) bool { return results[i].Score > results[j].Score })
-
-	seen := make(map[string]struct{}, len(results))
-	deduped := make([]Result, 0, len(results))
-	for _, result := range results {
-		if _, ok := seen[result.Source]; ok {
-			continue
-		}
-		seen[result.Source] = struct{}{}
-		deduped = append(deduped, result)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
 	}
-	if len(deduped) > limit {
-		deduped = deduped[:limit]
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s status %d: %s", strings.TrimPrefix(endpoint, "/"), resp.StatusCode, strings.TrimSpace(string(body)))
 	}
-	return deduped, nil
+
+	return body, nil
 }
 
 type embedRequest struct {
@@ -140,65 +184,31 @@ type embedResponse struct {
 	Dim     int         `json:"dim"`
 }
 
-type fileEmbeddings struct {
-	Source string           `json:"source"`
-	Model  string           `json:"model"`
-	Dim    int              `json:"dim"`
-	Chunks []chunkEmbedding `json:"chunks"`
+type rerankRequest struct {
+	Query      string   `json:"query"`
+	Candidates []string `json:"candidates"`
+	Normalize  bool     `json:"normalize"`
 }
 
-type chunkEmbedding struct {
-	Index  int       `json:"index"`
-	Text   string    `json:"text"`
-	Vector []float32 `json:"vector"`
+type rerankResponse struct {
+	Scores []float64 `json:"scores"`
+	Model  string    `json:"model"`
 }
 
-func searchEmbeddings(root fs.FS, query []float32) ([]Result, error) {
-	if len(query) == 0 {
-		return nil, errors.New("empty query vector")
-	}
-
-	queryNorm := vectorNorm(query)
-	if queryNorm == 0 {
-		return nil, errors.New("zero query vector norm")
-	}
-
-	var results []Result
-	err := fs.WalkDir(root, ".", func(path string, entry fs.DirEntry, walkErr error) error {
-		if walkErr != nil {
-			return walkErr
-		}
-		if entry.IsDir() {
-			return nil
-		}
-		if filepath.Ext(path) != ".json" {
-			return nil
-		}
-
-		data, err := fs.ReadFile(root, path)
-		if err != nil {
-			return err
-		}
-
-		var file fileEmbeddings
-		if err := json.Unmarshal(data, &file); err != nil {
-			return nil
-		}
+type HealthResponse struct {
+	Status         string `json:"status"`
+	EmbeddingModel string `json:"embedding_model"`
+	RerankModel    string `json:"rerank_model"`
+}
 
-		for _, chunk := range file.Chunks {
-			score := cosineSimilarity(query, queryNorm, chunk.Vector)
-			results = append(results, Result{
-				Source: trimDocsLangPrefix(file.Source),
-				Score:  score,
-			})
+// rank orders the scored chunks, keeps only the best chunk of every document and cuts the list to the limit.
+func sortChunks(candidates []scoredChunk) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score > candidates[j].score
 		}
-		return nil
+		return candidates[i].chunk.source < candidates[j].chunk.source
 	})
-	if err != nil {
-		return nil, err
-	}
-
-	return results, nil
 }
 
 func trimDocsLangPrefix(source string) string {
@@ -217,7 +227,7 @@ func trimDocsLangPrefix(source string) string {
 	return normalized
 }
 
-func cosineSimilarity(query []float32, queryNorm float32, candidate []float32) float32 {
+func cosineSimilarity(query []float32, queryNorm float32, candidate []float32, candidateNorm float32) float32 {
 	if len(candidate) == 0 {
 		return 0
 	}
@@ -228,7 +238,7 @@ func cosineSimilarity(query []float32, queryNorm float32, candidate []float32) f
 		}
 		dot += v * candidate[i]
 	}
-	denom := queryNorm * vectorNorm(candidate)
+	denom := queryNorm * candidateNorm
 	if denom == 0 {
 		return 0
 	}
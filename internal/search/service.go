@@ -1,17 +1,34 @@
 package search
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
 	"io/fs"
+	"log/slog"
 	"net/http"
-	"os"
 	"path"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
-	"SfosBeginnerGuide/internal/helper"
+	"SfosBeginnerGuide/internal/analytics"
+	"SfosBeginnerGuide/internal/cache"
+	"SfosBeginnerGuide/internal/config"
+	"SfosBeginnerGuide/internal/metrics"
+	"SfosBeginnerGuide/internal/tracing"
+)
+
+const (
+	rerankCandidates = 50
+	// the health of the embeddings server is checked again in the background once it's older than this
+	healthMaxAge       = 30 * time.Second
+	healthCheckTimeout = 5 * time.Second
+	// rankings are kept so that paging through the results doesn't search again
+	rankingCacheSize = 1000
+	rankingCacheTTL  = 10 * time.Minute
 )
 
 var (
@@ -24,19 +41,81 @@ var (
 type Service struct {
 	Root   fs.FS
 	Client *Client
+	Config config.SearchConfig
+	// Metadata is needed for the tags and actions filters
+	Metadata MetadataProvider
+	// Analytics receives the first page of every search, nil disables recording. It's called while
+	// responding, so it should not block (see analytics.AsyncSink).
+	Analytics analytics.Sink
+
+	indexesMu sync.Mutex
+	indexes   map[string]*Index
+	rankings  cache.Store[*ranking]
+
+	// health is the last known status of the embeddings server, healthChecked is closed once a running
+	// check is done and nil when no check is running
+	healthMu      sync.Mutex
+	health        *DependencyStatus
+	healthChecked chan struct{}
 }
 
-func NewService(root fs.FS) *Service {
-	return &Service{Root: root}
+type Status struct {
+	Enabled         bool              `json:"enabled"`
+	Available       bool              `json:"available"`
+	Rerank          bool              `json:"rerank"`
+	LexicalFallback bool              `json:"lexicalFallback"`
+	Embeddings      *DependencyStatus `json:"-"`
 }
 
-func (receiver *Service) Search(ctx context.Context, language, query string, limit int) ([]Result, error) {
-	if !helper.BoolEnv("CAPABILITY_SEARCHING", false) {
-		return nil, ErrSearchDisabled
+type DependencyStatus struct {
+	Configured     bool      `json:"configured"`
+	Healthy        bool      `json:"healthy"`
+	EmbeddingModel string    `json:"embeddingModel,omitempty"`
+	RerankModel    string    `json:"rerankModel,omitempty"`
+	Error          string    `json:"error,omitempty"`
+	CheckedAt      time.Time `json:"checkedAt"`
+}
+
+func NewService(root fs.FS, cfg config.SearchConfig) *Service {
+	service := &Service{
+		Root:     root,
+		Config:   cfg,
+		indexes:  make(map[string]*Index),
+		rankings: metrics.InstrumentCache("search", cache.NewLRU[*ranking](rankingCacheSize, rankingCacheTTL)),
 	}
+	if cfg.EmbeddingsServer != "" {
+		service.Client = &Client{
+			BaseURL: cfg.EmbeddingsServer,
+			HTTP:    &http.Client{Timeout: cfg.EmbeddingsTimeout.Std()},
+		}
+	}
+	return service
+}
 
-	baseURL := strings.TrimSpace(os.Getenv("EMBEDDINGS_SERVER"))
-	if baseURL == "" {
+func (receiver *Service) Search(ctx context.Context, request Request) (*Response, error) {
+	return receiver.search(ctx, request, nil)
+}
+
+// SearchStream calls emit with the results as soon as they are known: when reranking, the cosine similarity
+// results are emitted first as StageInitial, the reranked ones always last as StageFinal.
+func (receiver *Service) SearchStream(ctx context.Context, request Request, emit EmitFunc) error {
+	_, err := receiver.search(ctx, request, emit)
+	return err
+}
+
+func (receiver *Service) search(ctx context.Context, request Request, emit EmitFunc) (response *Response, err error) {
+	ctx, span := tracing.Start(ctx, "search.Search")
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+	language, query := request.Language, request.Query
+	span.SetAttribute("search.language", language)
+
+	if !receiver.Config.Enabled {
+		return nil, ErrSearchDisabled
+	}
+	if receiver.Client == nil && !receiver.Config.LexicalFallback {
 		return nil, ErrEmbeddingsServerMissing
 	}
 
@@ -47,24 +126,460 @@ func (receiver *Service) Search(ctx context.Context, language, query string, lim
 		return nil, errors.New("query is required")
 	}
 
+	sortBy := cmp.Or(request.Sort, SortRelevance)
+	if sortBy != SortRelevance && sortBy != SortTitle && sortBy != SortPath {
+		return nil, fmt.Errorf("unknown sort %s", sortBy)
+	}
+	key := rankingKey(request, sortBy)
+	var after *cursor
+	if request.Cursor != "" {
+		if after, err = decodeCursor(request.Cursor, key, sortBy); err != nil {
+			return nil, err
+		}
+	}
+
+	limit := request.Limit
+	if limit <= 0 {
+		limit = receiver.Config.DefaultResults
+	}
+	limit = min(limit, receiver.Config.MaxResults)
+
+	respond := func(ranked *ranking) *Response {
+		page := &Response{
+			Facets:     ranked.facets,
+			Total:      len(ranked.results),
+			Suggestion: ranked.suggestion,
+			Corrected:  ranked.corrected,
+		}
+		page.Results, page.Next = ranked.paginate(key, sortBy, after, limit)
+		return page
+	}
+	finish := func(response *Response) (*Response, error) {
+		span.SetAttribute("search.results", len(response.Results))
+		if emit != nil {
+			if err := emit(StageFinal, response); err != nil {
+				return nil, err
+			}
+		}
+		return response, nil
+	}
+
+	// following pages use the ranking of the first one when it's still cached, otherwise it's computed again
+	// and the page starts after the position in the cursor all the same
+	if after != nil {
+		if ranked, ok := receiver.rankings.Get(key); ok {
+			span.SetAttribute("search.cached", true)
+			return finish(respond(ranked))
+		}
+	}
+
+	index, err := receiver.index(ctx, language)
+	if err != nil {
+		return nil, err
+	}
+
+	analyzed := index.analyze(query)
+	span.SetAttribute("search.corrected", analyzed.suggestion != "")
+
+	emitInitial := func(initial *ranking) error {
+		if emit == nil {
+			return nil
+		}
+		return emit(StageInitial, respond(initial))
+	}
+
+	searched := searchedQuery{text: query, expanded: analyzed.expanded, suggestion: analyzed.suggestion}
+	ranked, mode, err := receiver.rank(ctx, index, request, sortBy, searched, emitInitial)
+	if err != nil {
+		return nil, err
+	}
+	// the suggestion is only searched for when the query as written finds nothing
+	if len(ranked.results) == 0 && analyzed.suggestion != "" {
+		searched = searchedQuery{
+			text:       analyzed.suggestion,
+			expanded:   analyzed.expandedSuggestion,
+			suggestion: analyzed.suggestion,
+			corrected:  true,
+		}
+		if ranked, mode, err = receiver.rank(ctx, index, request, sortBy, searched, emitInitial); err != nil {
+			return nil, err
+		}
+	}
+	receiver.rankings.Set(key, ranked)
+
+	response = respond(ranked)
+	if after == nil {
+		receiver.record(ctx, analytics.Event{
+			Time:      time.Now().UTC(),
+			Language:  language,
+			Query:     analytics.Anonymize(query),
+			Results:   response.Total,
+			TopScore:  ranked.topScore,
+			Mode:      mode,
+			Corrected: ranked.corrected,
+		})
+	}
+	return finish(response)
+}
+
+// searchedQuery is the query a ranking is computed for.
+type searchedQuery struct {
+	// text is the query as written (or the suggestion), it's matched by the lexical search and
+	// the best results are reranked against it
+	text string
+	// expanded is the query with synonyms, it's embedded
+	expanded   string
+	suggestion string
+	// corrected tells that text is the suggestion because the query as written found nothing
+	corrected bool
+}
+
+// rank finds the documents relevant to the query which match the filters and orders them, when reranking,
+// the ranking before reranking is passed to emitInitial. The returned mode is semantic or lexical.
+func (receiver *Service) rank(
+	ctx context.Context,
+	index *Index,
+	request Request,
+	sortBy string,
+	query searchedQuery,
+	emitInitial func(initial *ranking) error,
+) (*ranking, string, error) {
+	candidates, semantic, err := receiver.candidates(ctx, index, query)
+	if err != nil {
+		return nil, "", err
+	}
+	candidates = receiver.relevant(candidates, semantic)
+	mode := "lexical"
+	if semantic {
+		mode = "semantic"
+	}
+	searchRequests.Inc(request.Language, mode)
+	tracing.SpanFromContext(ctx).SetAttribute("search.mode", mode)
+
+	candidates, err = receiver.filterByMetadata(ctx, request.Language, candidates, request.Filters)
+	if err != nil {
+		return nil, "", err
+	}
+	facets := Facets{Directories: directoryFacets(candidates)}
+	candidates = filterByPath(candidates, request.Filters.PathPrefix)
+
+	newRanking := func(candidates []scoredChunk) (*ranking, error) {
+		rankCtx, rankSpan := tracing.Start(ctx, "search.rank")
+		defer rankSpan.End()
+		rankSpan.SetAttribute("search.candidates", len(candidates))
+		rankSpan.SetAttribute("search.sort", sortBy)
+
+		results, err := receiver.sortable(rankCtx, request.Language, candidates, sortBy)
+		if err != nil {
+			return nil, err
+		}
+		sortResults(results, sortBy)
+		return &ranking{
+			results:    results,
+			facets:     facets,
+			suggestion: query.suggestion,
+			corrected:  query.corrected,
+			topScore:   topScore(candidates),
+		}, nil
+	}
+
+	if semantic && receiver.Config.Rerank && len(candidates) > 0 {
+		initial, err := newRanking(candidates)
+		if err != nil {
+			return nil, "", err
+		}
+		if err := emitInitial(initial); err != nil {
+			return nil, "", err
+		}
+		candidates = receiver.rerank(ctx, query.text, candidates)
+		// after a timeout the cosine results are still worth returning, but nobody waits for them after a cancellation
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return nil, "", ctx.Err()
+		}
+	}
+
+	ranked, err := newRanking(candidates)
+	if err != nil {
+		return nil, "", err
+	}
+	return ranked, mode, nil
+}
+
+// Status reports whether searching actually works right now, not just whether it's enabled.
+func (receiver *Service) Status(ctx context.Context) Status {
+	status := Status{
+		Enabled:         receiver.Config.Enabled,
+		LexicalFallback: receiver.Config.Enabled && receiver.Config.LexicalFallback,
+	}
+	if !status.Enabled {
+		return status
+	}
+
+	status.Embeddings = receiver.EmbeddingsHealth(ctx)
+	status.Available = status.Embeddings.Healthy || status.LexicalFallback
+	status.Rerank = receiver.Config.Rerank && status.Embeddings.Healthy
+
+	return status
+}
+
+// EmbeddingsHealth returns the last known status of the embeddings server without waiting for it, an outdated
+// status is refreshed in the background by a single check. Only the very first calls wait for the first check.
+func (receiver *Service) EmbeddingsHealth(ctx context.Context) *DependencyStatus {
+	if receiver.Client == nil {
+		return &DependencyStatus{CheckedAt: time.Now()}
+	}
+
+	receiver.healthMu.Lock()
+	status := receiver.health
+	if (status == nil || time.Since(status.CheckedAt) > healthMaxAge) && receiver.healthChecked == nil {
+		receiver.healthChecked = make(chan struct{})
+		go receiver.checkHealth(receiver.healthChecked)
+	}
+	checked := receiver.healthChecked
+	receiver.healthMu.Unlock()
+	if status != nil {
+		return status
+	}
+
+	select {
+	case <-checked:
+	case <-ctx.Done():
+		return &DependencyStatus{Configured: true, Error: ctx.Err().Error(), CheckedAt: time.Now()}
+	}
+	receiver.healthMu.Lock()
+	defer receiver.healthMu.Unlock()
+	return receiver.health
+}
+
+// checkHealth asks the embeddings server for its health and closes checked once the status is stored.
+func (receiver *Service) checkHealth(checked chan struct{}) {
+	// the check outlives the request which started it
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+
+	status := &DependencyStatus{Configured: true}
+	health, err := receiver.Client.Health(ctx)
+	if err != nil {
+		status.Error = err.Error()
+	} else {
+		status.Healthy = health.Status == "ok"
+		status.EmbeddingModel = health.EmbeddingModel
+		status.RerankModel = health.RerankModel
+	}
+	status.CheckedAt = time.Now()
+
+	receiver.healthMu.Lock()
+	defer receiver.healthMu.Unlock()
+	receiver.health = status
+	receiver.healthChecked = nil
+	close(checked)
+}
+
+// CheckIndex loads the search index of the language and fails when it has no documents.
+func (receiver *Service) CheckIndex(ctx context.Context, language string) error {
+	index, err := receiver.index(ctx, language)
+	if err != nil {
+		return err
+	}
+	if index.Len() == 0 {
+		return fmt.Errorf("search index for %s is empty", language)
+	}
+	return nil
+}
+
+// CheckEmbeddings asks the embeddings server for its health, unlike the last known status used by Status.
+func (receiver *Service) CheckEmbeddings(ctx context.Context) error {
+	if receiver.Client == nil {
+		return ErrEmbeddingsServerMissing
+	}
+
+	health, err := receiver.Client.Health(ctx)
+	if err != nil {
+		return err
+	}
+	if health.Status != "ok" {
+		return fmt.Errorf("embeddings server reports status %q", health.Status)
+	}
+	return nil
+}
+
+// candidates scores the documents, the expanded query is embedded while the lexical search expands the query
+// as written itself.
+func (receiver *Service) candidates(ctx context.Context, index *Index, query searchedQuery) ([]scoredChunk, bool, error) {
+	if receiver.Client == nil {
+		return receiver.scanLexical(ctx, index, query.text), false, nil
+	}
+
+	queryVector, err := receiver.Client.EmbedQuery(ctx, query.expanded)
+	if err != nil {
+		if !receiver.Config.LexicalFallback || ctx.Err() != nil {
+			return nil, false, err
+		}
+		slog.WarnContext(ctx, "embedding the query failed, falling back to lexical search", "error", err)
+		return receiver.scanLexical(ctx, index, query.text), false, nil
+	}
+
+	_, span := tracing.Start(ctx, "search.index.scan")
+	defer span.End()
+	span.SetAttribute("search.mode", "semantic")
+	span.SetAttribute("search.chunks", index.Len())
+
+	candidates, err := index.searchVector(queryVector)
+	if err != nil {
+		span.RecordError(err)
+		return nil, false, err
+	}
+	return candidates, true, nil
+}
+
+// relevant keeps the candidates related to the query, best first. The semantic search scores every document,
+// so the ones below the minimum score are dropped, and in both modes only the best MaxCandidates are kept.
+func (receiver *Service) relevant(candidates []scoredChunk, semantic bool) []scoredChunk {
+	if semantic {
+		candidates = slices.DeleteFunc(candidates, func(candidate scoredChunk) bool {
+			return float64(candidate.score) < receiver.Config.MinScore
+		})
+	}
+	return bestChunks(candidates, receiver.Config.MaxCandidates)
+}
+
+func (receiver *Service) scanLexical(ctx context.Context, index *Index, query string) []scoredChunk {
+	_, span := tracing.Start(ctx, "search.index.scan")
+	defer span.End()
+	span.SetAttribute("search.mode", "lexical")
+	span.SetAttribute("search.chunks", index.Len())
+
+	return index.searchLexical(query)
+}
+
+// rerank orders the best candidates by their cross-encoder scores, the remaining candidates follow in their
+// original order so that every relevant document stays reachable by paging. On failure the candidates
+// are returned unchanged.
+func (receiver *Service) rerank(ctx context.Context, query string, candidates []scoredChunk) []scoredChunk {
+	best := candidates[:min(len(candidates), rerankCandidates)]
+	if len(best) == 0 {
+		return candidates
+	}
+
+	texts := make([]string, len(best))
+	for i, candidate := range best {
+		texts[i] = candidate.chunk.text
+	}
+
+	scores, err := receiver.Client.Rerank(ctx, query, texts)
+	if err != nil {
+		if !errors.Is(ctx.Err(), context.Canceled) {
+			slog.WarnContext(ctx, "reranking failed, using cosine similarity", "error", err)
+		}
+		return candidates
+	}
+
+	reranked := slices.Clone(best)
+	for i := range reranked {
+		reranked[i].score = scores[i]
+		reranked[i].reranked = true
+	}
+	sortChunks(reranked)
+	return append(reranked, candidates[len(best):]...)
+}
+
+func (receiver *Service) index(ctx context.Context, language string) (*Index, error) {
 	if receiver.Root == nil {
 		return nil, ErrAssetsUnavailable
 	}
 
-	langFS, err := fs.Sub(receiver.Root, path.Join("docs", language))
+	languagePath := path.Join("docs", language)
+	if !fs.ValidPath(languagePath) || strings.Contains(language, "/") {
+		return nil, fmt.Errorf("%w: %s", ErrLanguageNotFound, language)
+	}
+
+	receiver.indexesMu.Lock()
+	defer receiver.indexesMu.Unlock()
+
+	if index, ok := receiver.indexes[language]; ok {
+		return index, nil
+	}
+
+	if stat, err := fs.Stat(receiver.Root, languagePath); err != nil || !stat.IsDir() {
+		return nil, fmt.Errorf("%w: %s", ErrLanguageNotFound, language)
+	}
+	langFS, err := fs.Sub(receiver.Root, languagePath)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %s", ErrLanguageNotFound, language)
 	}
 
-	client := receiver.Client
-	if client == nil {
-		client = &Client{
-			BaseURL: baseURL,
-			HTTP:    &http.Client{Timeout: 60 * time.Second},
+	index, err := LoadIndex(langFS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load search index for %s: %w", language, err)
+	}
+	if receiver.Metadata != nil {
+		// the index is shared by all the requests, so it can't depend on the first one not being cancelled
+		metadataCtx := context.WithoutCancel(ctx)
+		for _, source := range index.sources() {
+			meta, err := receiver.Metadata.DocumentMeta(metadataCtx, language, source)
+			if err != nil {
+				slog.WarnContext(ctx, "failed indexing document metadata", "source", source, "error", err)
+				continue
+			}
+			index.addDocumentTerms(source, meta.terms())
 		}
-	} else if strings.TrimSpace(client.BaseURL) == "" {
-		client.BaseURL = baseURL
 	}
+	synonyms, err := LoadSynonyms(langFS, synonymsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load search index for %s: %w", language, err)
+	}
+	index.prepareQueries(synonyms)
+	receiver.indexes[language] = index
+
+	return index, nil
+}
+
+func (receiver *Service) record(ctx context.Context, event analytics.Event) {
+	if receiver.Analytics == nil {
+		return
+	}
+	if err := receiver.Analytics.Record(ctx, event); err != nil {
+		slog.WarnContext(ctx, "failed recording search analytics", "error", err)
+	}
+}
 
-	return Search(ctx, langFS, client, query, limit)
+func topScore(candidates []scoredChunk) float32 {
+	var result float32
+	for i, candidate := range candidates {
+		if i == 0 || candidate.score > result {
+			result = candidate.score
+		}
+	}
+	return result
+}
+
+// sortable converts the candidates to results, with their titles when sorting by title.
+func (receiver *Service) sortable(ctx context.Context, language string, candidates []scoredChunk, sortBy string) ([]*sortableResult, error) {
+	results := make([]*sortableResult, 0, len(candidates))
+	for _, candidate := range candidates {
+		result := &sortableResult{
+			Result:   Result{Source: candidate.chunk.source, Score: candidate.score},
+			reranked: candidate.reranked,
+		}
+		if sortBy == SortTitle && receiver.Metadata != nil {
+			meta, err := receiver.Metadata.DocumentMeta(ctx, language, candidate.chunk.source)
+			if err != nil {
+				return nil, fmt.Errorf("failed reading metadata of %s: %w", candidate.chunk.source, err)
+			}
+			result.title = meta.Title
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// bestChunks returns the limit best candidates sorted by their scores, the index already keeps only
+// the best chunk of every document.
+func bestChunks(candidates []scoredChunk, limit int) []scoredChunk {
+	result := slices.Clone(candidates)
+	sortChunks(result)
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result
 }
@@ -0,0 +1,88 @@
+package search
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"SfosBeginnerGuide/internal/config"
+)
+
+func TestEmbeddingsHealthChecksOnceInTheBackground(t *testing.T) {
+	const checkDuration = 200 * time.Millisecond
+	var checks atomic.Int32
+	embeddings := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path != "/health" {
+			http.NotFound(writer, request)
+			return
+		}
+		checks.Add(1)
+		time.Sleep(checkDuration)
+		_, _ = writer.Write([]byte(`{"status": "ok", "embedding_model": "test"}`))
+	}))
+	t.Cleanup(embeddings.Close)
+
+	cfg := config.Default().Search
+	cfg.Enabled = true
+	cfg.EmbeddingsServer = embeddings.URL
+	service := NewService(nil, cfg)
+
+	concurrently := func() []*DependencyStatus {
+		statuses := make([]*DependencyStatus, 10)
+		var wait sync.WaitGroup
+		for i := range statuses {
+			wait.Add(1)
+			go func() {
+				defer wait.Done()
+				statuses[i] = service.EmbeddingsHealth(context.Background())
+			}()
+		}
+		wait.Wait()
+		return statuses
+	}
+
+	// nothing is known yet, the first callers wait for a single check
+	for _, status := range concurrently() {
+		if !status.Healthy || status.EmbeddingModel != "test" {
+			t.Errorf("status %+v, expected healthy", status)
+		}
+	}
+	if checks.Load() != 1 {
+		t.Errorf("checked %d times, expected once", checks.Load())
+	}
+
+	// an outdated status is returned right away while a single check runs in the background
+	service.healthMu.Lock()
+	outdated := *service.health
+	outdated.CheckedAt = time.Now().Add(-2 * healthMaxAge)
+	service.health = &outdated
+	service.healthMu.Unlock()
+
+	start := time.Now()
+	for _, status := range concurrently() {
+		if status != &outdated {
+			t.Errorf("status %+v, expected the last known one", status)
+		}
+	}
+	if elapsed := time.Since(start); elapsed >= checkDuration {
+		t.Errorf("waited %s for the outdated status", elapsed)
+	}
+
+	service.healthMu.Lock()
+	checked := service.healthChecked
+	service.healthMu.Unlock()
+	if checked == nil {
+		t.Fatal("no check is running")
+	}
+	<-checked
+	if checks.Load() != 2 {
+		t.Errorf("checked %d times, expected twice", checks.Load())
+	}
+	if status := service.EmbeddingsHealth(context.Background()); status == &outdated || time.Since(status.CheckedAt) > healthMaxAge {
+		t.Errorf("status %+v wasn't refreshed", status)
+	}
+}
@@ -0,0 +1,263 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StdoutExporter writes every finished span as a JSON line, it's meant for local debugging.
+type StdoutExporter struct {
+	mu     sync.Mutex
+	writer io.Writer
+}
+
+func NewStdoutExporter(writer io.Writer) *StdoutExporter {
+	return &StdoutExporter{writer: writer}
+}
+
+func (receiver *StdoutExporter) Export(span SpanData) {
+	line := map[string]any{
+		"traceId":    span.TraceID.String(),
+		"spanId":     span.SpanID.String(),
+		"name":       span.Name,
+		"start":      span.Start,
+		"durationMs": float64(span.End.Sub(span.Start).Microseconds()) / 1000,
+		"attributes": span.Attributes,
+	}
+	if span.ParentID.IsValid() {
+		line["parentSpanId"] = span.ParentID.String()
+	}
+	if span.Error != "" {
+		line["error"] = span.Error
+	}
+
+	data, err := json.Marshal(line)
+	if err != nil {
+		return
+	}
+
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	_, _ = receiver.writer.Write(append(data, '\n'))
+}
+
+func (receiver *StdoutExporter) Shutdown(context.Context) error {
+	return nil
+}
+
+const (
+	otlpBatchSize     = 100
+	otlpQueueSize     = 2048
+	otlpFlushInterval = 5 * time.Second
+)
+
+// OTLPExporter sends the spans in batches to an OpenTelemetry collector using OTLP/HTTP with the JSON encoding.
+// Spans are dropped when the queue is full rather than slowing down the requests.
+type OTLPExporter struct {
+	endpoint    string
+	serviceName string
+	client      *http.Client
+
+	queue    chan SpanData
+	shutdown chan struct{}
+	done     chan struct{}
+	once     sync.Once
+}
+
+// NewOTLPExporter sends the spans to the endpoint, like http://localhost:4318, the /v1/traces path is appended.
+func NewOTLPExporter(endpoint string, serviceName string) *OTLPExporter {
+	exporter := &OTLPExporter{
+		endpoint:    strings.TrimRight(endpoint, "/") + "/v1/traces",
+		serviceName: serviceName,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		queue:       make(chan SpanData, otlpQueueSize),
+		shutdown:    make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	go exporter.run()
+	return exporter
+}
+
+func (receiver *OTLPExporter) Export(span SpanData) {
+	select {
+	case receiver.queue <- span:
+	default:
+	}
+}
+
+func (receiver *OTLPExporter) Shutdown(ctx context.Context) error {
+	receiver.once.Do(func() { close(receiver.shutdown) })
+	select {
+	case <-receiver.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (receiver *OTLPExporter) run() {
+	defer close(receiver.done)
+
+	ticker := time.NewTicker(otlpFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]SpanData, 0, otlpBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := receiver.send(batch); err != nil {
+			slog.Warn("failed exporting spans", "error", err, "spans", len(batch))
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case span := <-receiver.queue:
+			batch = append(batch, span)
+			if len(batch) >= otlpBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-receiver.shutdown:
+			for {
+				select {
+				case span := <-receiver.queue:
+					batch = append(batch, span)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+func (receiver *OTLPExporter) send(batch []SpanData) error {
+	spans := make([]otlpSpan, 0, len(batch))
+	for _, span := range batch {
+		spans = append(spans, newOTLPSpan(span))
+	}
+
+	payload := otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: []otlpAttribute{newOTLPAttribute("service.name", receiver.serviceName)}},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: "SfosBeginnerGuide"},
+			Spans: spans,
+		}},
+	}}}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	resp, err := receiver.client.Post(receiver.endpoint, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("collector responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              Kind            `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            *otlpStatus     `json:"status,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+func newOTLPSpan(span SpanData) otlpSpan {
+	result := otlpSpan{
+		TraceID:           span.TraceID.String(),
+		SpanID:            span.SpanID.String(),
+		Name:              span.Name,
+		Kind:              span.Kind,
+		StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+	}
+	if span.ParentID.IsValid() {
+		result.ParentSpanID = span.ParentID.String()
+	}
+	for key, value := range span.Attributes {
+		result.Attributes = append(result.Attributes, newOTLPAttribute(key, value))
+	}
+	if span.Error != "" {
+		// 2 is STATUS_CODE_ERROR
+		result.Status = &otlpStatus{Code: 2, Message: span.Error}
+	}
+	return result
+}
+
+func newOTLPAttribute(key string, value any) otlpAttribute {
+	var encoded map[string]any
+	switch typed := value.(type) {
+	case string:
+		encoded = map[string]any{"stringValue": typed}
+	case bool:
+		encoded = map[string]any{"boolValue": typed}
+	case int:
+		encoded = map[string]any{"intValue": strconv.Itoa(typed)}
+	case int64:
+		encoded = map[string]any{"intValue": strconv.FormatInt(typed, 10)}
+	case float32:
+		encoded = map[string]any{"doubleValue": float64(typed)}
+	case float64:
+		encoded = map[string]any{"doubleValue": typed}
+	default:
+		encoded = map[string]any{"stringValue": fmt.Sprint(typed)}
+	}
+	return otlpAttribute{Key: key, Value: encoded}
+}
@@ -0,0 +1,186 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// collectedRequest is the part of an OTLP/JSON export request the tests check.
+type collectedRequest struct {
+	ResourceSpans []struct {
+		Resource struct {
+			Attributes []struct {
+				Key   string `json:"key"`
+				Value struct {
+					StringValue string `json:"stringValue"`
+				} `json:"value"`
+			} `json:"attributes"`
+		} `json:"resource"`
+		ScopeSpans []struct {
+			Spans []collectedSpan `json:"spans"`
+		} `json:"scopeSpans"`
+	} `json:"resourceSpans"`
+}
+
+type collectedSpan struct {
+	TraceID      string `json:"traceId"`
+	SpanID       string `json:"spanId"`
+	ParentSpanID string `json:"parentSpanId"`
+	Name         string `json:"name"`
+	Kind         int    `json:"kind"`
+	Status       *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"status"`
+}
+
+type collector struct {
+	mu       sync.Mutex
+	requests []collectedRequest
+}
+
+func newCollector(t *testing.T) (*collector, *httptest.Server) {
+	result := &collector{}
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.Method != http.MethodPost || request.URL.Path != "/v1/traces" {
+			t.Errorf("unexpected request %s %s", request.Method, request.URL.Path)
+			writer.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if contentType := request.Header.Get("Content-Type"); contentType != "application/json" {
+			t.Errorf("unexpected content type %q", contentType)
+		}
+
+		var decoded collectedRequest
+		if err := json.NewDecoder(request.Body).Decode(&decoded); err != nil {
+			t.Errorf("failed decoding the export request: %v", err)
+			writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		result.mu.Lock()
+		result.requests = append(result.requests, decoded)
+		result.mu.Unlock()
+	}))
+	t.Cleanup(server.Close)
+	return result, server
+}
+
+func (receiver *collector) batches() [][]collectedSpan {
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+
+	var result [][]collectedSpan
+	for _, request := range receiver.requests {
+		var spans []collectedSpan
+		for _, resourceSpans := range request.ResourceSpans {
+			for _, scopeSpans := range resourceSpans.ScopeSpans {
+				spans = append(spans, scopeSpans.Spans...)
+			}
+		}
+		result = append(result, spans)
+	}
+	return result
+}
+
+func shutdown(t *testing.T, exporter Exporter) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := exporter.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+}
+
+func TestOTLPExporterSendsSpansOnShutdown(t *testing.T) {
+	collected, server := newCollector(t)
+	exporter := NewOTLPExporter(server.URL+"/", "guide-test")
+	SetDefault(NewTracer(exporter))
+	t.Cleanup(func() { SetDefault(nil) })
+
+	ctx, parent := StartKind(context.Background(), "GET /api/v1/content/{lang}/{path...}", KindServer)
+	_, child := Start(ctx, "content.ParseByPath")
+	child.SetAttribute("path", "en/index.md")
+	child.RecordError(context.Canceled)
+	child.End()
+	parent.End()
+
+	if batches := collected.batches(); len(batches) != 0 {
+		t.Fatalf("spans were sent before the batch was full or flushed: %d batches", len(batches))
+	}
+	shutdown(t, exporter)
+
+	collected.mu.Lock()
+	requests := collected.requests
+	collected.mu.Unlock()
+	if len(requests) != 1 {
+		t.Fatalf("expected 1 export request, got %d", len(requests))
+	}
+	resource := requests[0].ResourceSpans[0].Resource
+	if len(resource.Attributes) != 1 || resource.Attributes[0].Key != "service.name" || resource.Attributes[0].Value.StringValue != "guide-test" {
+		t.Errorf("unexpected resource attributes %+v", resource.Attributes)
+	}
+
+	spans := collected.batches()[0]
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	childSpan, parentSpan := spans[0], spans[1]
+	if childSpan.Name != "content.ParseByPath" || parentSpan.Name != "GET /api/v1/content/{lang}/{path...}" {
+		t.Errorf("unexpected span names %q and %q", childSpan.Name, parentSpan.Name)
+	}
+	if parentSpan.ParentSpanID != "" {
+		t.Errorf("root span has parent %q", parentSpan.ParentSpanID)
+	}
+	if childSpan.ParentSpanID != parentSpan.SpanID || childSpan.ParentSpanID != parent.data.SpanID.String() {
+		t.Errorf("child span parent %q, expected %q", childSpan.ParentSpanID, parentSpan.SpanID)
+	}
+	if childSpan.TraceID != parentSpan.TraceID || len(childSpan.TraceID) != 32 || len(childSpan.SpanID) != 16 {
+		t.Errorf("unexpected ids: trace %q/%q, span %q", childSpan.TraceID, parentSpan.TraceID, childSpan.SpanID)
+	}
+	if parentSpan.Kind != int(KindServer) || childSpan.Kind != int(KindInternal) {
+		t.Errorf("unexpected kinds %d and %d", parentSpan.Kind, childSpan.Kind)
+	}
+	if childSpan.Status == nil || childSpan.Status.Code != 2 || childSpan.Status.Message != context.Canceled.Error() {
+		t.Errorf("unexpected child status %+v", childSpan.Status)
+	}
+	if parentSpan.Status != nil {
+		t.Errorf("unexpected parent status %+v", parentSpan.Status)
+	}
+}
+
+func TestOTLPExporterBatchesSpans(t *testing.T) {
+	collected, server := newCollector(t)
+	exporter := NewOTLPExporter(server.URL, "guide-test")
+
+	total := otlpBatchSize + 5
+	for i := range total {
+		exporter.Export(SpanData{
+			TraceID: newTraceID(),
+			SpanID:  newSpanID(),
+			Name:    "span",
+			Kind:    KindInternal,
+			Start:   time.Now(),
+			End:     time.Now(),
+		})
+		if i == otlpBatchSize-1 {
+			// the full batch is sent right away, without waiting for the flush interval
+			deadline := time.Now().Add(5 * time.Second)
+			for len(collected.batches()) == 0 && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+			}
+		}
+	}
+	shutdown(t, exporter)
+
+	batches := collected.batches()
+	if len(batches) != 2 {
+		t.Fatalf("expected 2 batches, got %d", len(batches))
+	}
+	if len(batches[0]) != otlpBatchSize || len(batches[1]) != total-otlpBatchSize {
+		t.Errorf("unexpected batch sizes %d and %d", len(batches[0]), len(batches[1]))
+	}
+}
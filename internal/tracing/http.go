@@ -0,0 +1,38 @@
+package tracing
+
+import (
+	"net/http"
+	"strings"
+
+	"SfosBeginnerGuide/internal/httpx"
+)
+
+// Middleware starts a server span for every request, joining the trace of the caller when it sent
+// a traceparent header. The span is named after the route matched by the mux, so it has to wrap the mux.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		ctx := Extract(request.Context(), request.Header)
+		ctx, span := StartKind(ctx, request.Method, KindServer)
+		defer span.End()
+
+		traced := request.WithContext(ctx)
+		recorder := httpx.NewResponseRecorder(writer)
+		next.ServeHTTP(recorder, traced)
+
+		// the route is only known after the mux matched it, outer middlewares see it too
+		request.Pattern = traced.Pattern
+		switch {
+		case strings.Contains(traced.Pattern, " "):
+			span.SetName(traced.Pattern)
+		case traced.Pattern != "":
+			span.SetName(request.Method + " " + traced.Pattern)
+		}
+		span.SetAttribute("http.request.method", request.Method)
+		span.SetAttribute("url.path", request.URL.Path)
+		span.SetAttribute("http.route", traced.Pattern)
+		span.SetAttribute("http.response.status_code", recorder.Status)
+		if id := httpx.RequestID(ctx); id != "" {
+			span.SetAttribute("request.id", id)
+		}
+	})
+}
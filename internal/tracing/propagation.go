@@ -0,0 +1,44 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+const TraceparentHeader = "traceparent"
+
+// Extract remembers the parent from the W3C traceparent header, invalid headers are ignored
+// and a new trace is started instead.
+func Extract(ctx context.Context, header http.Header) context.Context {
+	parts := strings.Split(strings.TrimSpace(header.Get(TraceparentHeader)), "-")
+	if len(parts) != 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return ctx
+	}
+
+	var parent remoteParent
+	if !decodeHex(parts[1], parent.traceID[:]) || !decodeHex(parts[2], parent.spanID[:]) {
+		return ctx
+	}
+	if !parent.traceID.IsValid() || !parent.spanID.IsValid() {
+		return ctx
+	}
+
+	return context.WithValue(ctx, remoteParentKey{}, parent)
+}
+
+// Inject sets the traceparent header for the span in the context, so that the callee joins the trace.
+func Inject(ctx context.Context, header http.Header) {
+	if span := SpanFromContext(ctx); span != nil {
+		header.Set(TraceparentHeader, span.Traceparent())
+	}
+}
+
+func decodeHex(value string, target []byte) bool {
+	if len(value) != hex.EncodedLen(len(target)) || strings.ToLower(value) != value {
+		return false
+	}
+	_, err := hex.Decode(target, []byte(value))
+	return err == nil
+}
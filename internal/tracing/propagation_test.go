@@ -0,0 +1,75 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+type discardExporter struct{}
+
+func (discardExporter) Export(SpanData)                {}
+func (discardExporter) Shutdown(context.Context) error { return nil }
+
+func TestTraceparentRoundTrip(t *testing.T) {
+	SetDefault(NewTracer(discardExporter{}))
+	t.Cleanup(func() { SetDefault(nil) })
+
+	const traceID, parentID = "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"
+	incoming := http.Header{}
+	incoming.Set(TraceparentHeader, "00-"+traceID+"-"+parentID+"-01")
+
+	ctx, span := Start(Extract(context.Background(), incoming), "request")
+	if span.TraceID().String() != traceID {
+		t.Errorf("trace id %s, expected %s", span.TraceID(), traceID)
+	}
+	if span.data.ParentID.String() != parentID {
+		t.Errorf("parent id %s, expected %s", span.data.ParentID, parentID)
+	}
+
+	outgoing := http.Header{}
+	Inject(ctx, outgoing)
+	expected := "00-" + traceID + "-" + span.data.SpanID.String() + "-01"
+	if actual := outgoing.Get(TraceparentHeader); actual != expected {
+		t.Errorf("injected %q, expected %q", actual, expected)
+	}
+
+	// the injected header continues the same trace on the other side
+	_, remote := Start(Extract(context.Background(), outgoing), "callee")
+	if remote.TraceID() != span.TraceID() || remote.data.ParentID != span.data.SpanID {
+		t.Errorf("callee span %s/%s doesn't continue %s/%s", remote.TraceID(), remote.data.ParentID, span.TraceID(), span.data.SpanID)
+	}
+}
+
+func TestExtractIgnoresInvalidTraceparent(t *testing.T) {
+	SetDefault(NewTracer(discardExporter{}))
+	t.Cleanup(func() { SetDefault(nil) })
+
+	for _, value := range []string{
+		"",
+		"garbage",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6-00f067aa0ba902b7-01",
+	} {
+		header := http.Header{}
+		header.Set(TraceparentHeader, value)
+		_, span := Start(Extract(context.Background(), header), "request")
+		if span.data.ParentID.IsValid() {
+			t.Errorf("%q: span has parent %s", value, span.data.ParentID)
+		}
+		if span.TraceID().String() == "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("%q: trace id was taken from an invalid header", value)
+		}
+	}
+}
+
+func TestInjectWithoutSpan(t *testing.T) {
+	header := http.Header{}
+	Inject(context.Background(), header)
+	if value := header.Get(TraceparentHeader); value != "" {
+		t.Errorf("unexpected traceparent %q", value)
+	}
+}
@@ -0,0 +1,213 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type TraceID [16]byte
+
+func (receiver TraceID) String() string {
+	return hex.EncodeToString(receiver[:])
+}
+
+func (receiver TraceID) IsValid() bool {
+	return receiver != TraceID{}
+}
+
+type SpanID [8]byte
+
+func (receiver SpanID) String() string {
+	return hex.EncodeToString(receiver[:])
+}
+
+func (receiver SpanID) IsValid() bool {
+	return receiver != SpanID{}
+}
+
+// SpanData is a finished span as handed to the exporters.
+type SpanData struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	ParentID   SpanID
+	Name       string
+	Kind       Kind
+	Start      time.Time
+	End        time.Time
+	Attributes map[string]any
+	Error      string
+}
+
+type Kind int
+
+const (
+	KindInternal Kind = iota + 1
+	KindServer
+	KindClient
+)
+
+// Span is a single timed operation. All methods are safe to call on a nil span, which is what Start
+// returns when tracing is disabled, so the instrumented code never has to check.
+type Span struct {
+	tracer *Tracer
+
+	mu   sync.Mutex
+	data SpanData
+	done bool
+}
+
+func (receiver *Span) SetName(name string) {
+	if receiver == nil {
+		return
+	}
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	receiver.data.Name = name
+}
+
+func (receiver *Span) SetAttribute(key string, value any) {
+	if receiver == nil {
+		return
+	}
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	receiver.data.Attributes[key] = value
+}
+
+func (receiver *Span) RecordError(err error) {
+	if receiver == nil || err == nil {
+		return
+	}
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	receiver.data.Error = err.Error()
+}
+
+func (receiver *Span) End() {
+	if receiver == nil {
+		return
+	}
+	receiver.mu.Lock()
+	if receiver.done {
+		receiver.mu.Unlock()
+		return
+	}
+	receiver.done = true
+	receiver.data.End = time.Now()
+	data := receiver.data
+	receiver.mu.Unlock()
+
+	receiver.tracer.exporter.Export(data)
+}
+
+func (receiver *Span) TraceID() TraceID {
+	if receiver == nil {
+		return TraceID{}
+	}
+	return receiver.data.TraceID
+}
+
+// Traceparent returns the W3C trace context header value identifying the span.
+func (receiver *Span) Traceparent() string {
+	if receiver == nil {
+		return ""
+	}
+	return fmt.Sprintf("00-%s-%s-01", receiver.data.TraceID, receiver.data.SpanID)
+}
+
+type Exporter interface {
+	Export(span SpanData)
+	Shutdown(ctx context.Context) error
+}
+
+type Tracer struct {
+	exporter Exporter
+}
+
+func NewTracer(exporter Exporter) *Tracer {
+	return &Tracer{exporter: exporter}
+}
+
+var (
+	defaultMu     sync.RWMutex
+	defaultTracer *Tracer
+)
+
+// SetDefault sets the tracer used by Start, with a nil tracer the tracing is disabled.
+func SetDefault(tracer *Tracer) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultTracer = tracer
+}
+
+func Default() *Tracer {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultTracer
+}
+
+type spanKey struct{}
+type remoteParentKey struct{}
+
+type remoteParent struct {
+	traceID TraceID
+	spanID  SpanID
+}
+
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	return StartKind(ctx, name, KindInternal)
+}
+
+// StartKind starts a span as a child of the span in the context, or of the remote parent extracted
+// from a traceparent header, or as a new trace.
+func StartKind(ctx context.Context, name string, kind Kind) (context.Context, *Span) {
+	tracer := Default()
+	if tracer == nil {
+		return ctx, nil
+	}
+
+	data := SpanData{
+		SpanID:     newSpanID(),
+		Name:       name,
+		Kind:       kind,
+		Start:      time.Now(),
+		Attributes: make(map[string]any),
+	}
+	if parent := SpanFromContext(ctx); parent != nil {
+		data.TraceID = parent.data.TraceID
+		data.ParentID = parent.data.SpanID
+	} else if remote, ok := ctx.Value(remoteParentKey{}).(remoteParent); ok {
+		data.TraceID = remote.traceID
+		data.ParentID = remote.spanID
+	} else {
+		data.TraceID = newTraceID()
+	}
+
+	span := &Span{tracer: tracer, data: data}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		_, _ = rand.Read(id[:])
+	}
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		_, _ = rand.Read(id[:])
+	}
+	return id
+}
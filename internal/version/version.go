@@ -0,0 +1,65 @@
+package version
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a dotted numeric version like 1.2.3 or 4.6.0.13, compared component by component.
+type Version []int
+
+func Parse(value string) (Version, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "v")
+	if cut := strings.IndexAny(value, "-+ "); cut >= 0 {
+		value = value[:cut]
+	}
+	if value == "" {
+		return nil, fmt.Errorf("empty version")
+	}
+
+	parts := strings.Split(value, ".")
+	result := make(Version, 0, len(parts))
+	for _, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil || number < 0 {
+			return nil, fmt.Errorf("invalid version %s", value)
+		}
+		result = append(result, number)
+	}
+
+	return result, nil
+}
+
+// Compare returns -1, 0 or 1; missing components are treated as zero, so 5.0 equals 5.0.0.
+func (receiver Version) Compare(other Version) int {
+	length := max(len(receiver), len(other))
+	for i := 0; i < length; i++ {
+		left, right := 0, 0
+		if i < len(receiver) {
+			left = receiver[i]
+		}
+		if i < len(other) {
+			right = other[i]
+		}
+		if left != right {
+			if left < right {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func (receiver Version) String() string {
+	parts := make([]string, len(receiver))
+	for i, part := range receiver {
+		parts[i] = strconv.Itoa(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+func (receiver Version) MarshalText() ([]byte, error) {
+	return []byte(receiver.String()), nil
+}
@@ -4,59 +4,216 @@ import (
 	"context"
 	"embed"
 	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
+	"SfosBeginnerGuide/internal/analytics"
+	"SfosBeginnerGuide/internal/assets"
+	"SfosBeginnerGuide/internal/clientinfo"
+	"SfosBeginnerGuide/internal/config"
 	"SfosBeginnerGuide/internal/content"
+	"SfosBeginnerGuide/internal/health"
 	"SfosBeginnerGuide/internal/httpapi"
+	"SfosBeginnerGuide/internal/httpx"
 	"SfosBeginnerGuide/internal/markdown"
+	"SfosBeginnerGuide/internal/metrics"
 	"SfosBeginnerGuide/internal/search"
+	"SfosBeginnerGuide/internal/tracing"
+
+	"github.com/yuin/goldmark"
 )
 
 //go:embed docs
 var docs embed.FS
 
 func main() {
+	validate := flag.Bool("validate", false, "validate the docs and exit")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration and exit")
+	searchReport := flag.Bool("search-report", false, "print a report of the recorded searches and exit")
+	lowScore := flag.Float64("low-score", 0.5, "top score below which --search-report lists a semantic search as low scoring")
+	lowLexicalScore := flag.Float64("low-lexical-score", 1, "share of the query's words below which --search-report lists a lexical search as low scoring")
+	flag.Parse()
+
 	gracefulShutdown := make(chan os.Signal, 1)
 	signal.Notify(gracefulShutdown, syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT)
 
-	port, ok := os.LookupEnv("APP_PORT")
-	if !ok {
-		port = "8080"
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	logger := slog.New(httpx.NewLogHandler(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: cfg.LogLevel})))
+	slog.SetDefault(logger)
+
+	if *printConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if *searchReport {
+		os.Exit(printSearchReport(cfg.Search.AnalyticsFile, map[string]float32{
+			"semantic": float32(*lowScore),
+			"lexical":  float32(*lowLexicalScore),
+		}))
+	}
+
+	exporter := newTraceExporter(cfg.Tracing)
+	if exporter != nil {
+		tracing.SetDefault(tracing.NewTracer(exporter))
+	}
+
+	images, err := assets.NewCachedImageStore(docs, "docs", cfg.Cache.ImageEntries, cfg.Cache.ImageTTL.Std())
+	if err != nil {
+		log.Fatal(fmt.Errorf("failed to load images: %w", err))
+	}
+
+	extensions := []goldmark.Extender{markdown.NewImageAttributes(images)}
+	md := markdown.New(extensions...)
+	qml := markdown.NewQML(extensions...)
+	parser := content.NewCachedMarkdownParser(docs, md, qml, cfg.Cache.ContentEntries, cfg.Cache.ContentTTL.Std())
+
+	actions, err := content.LoadActionRegistry(docs, "docs/actions.yaml")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	devices, err := content.LoadDeviceRegistry(docs, "docs/devices.yaml")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	redirects, err := content.LoadRedirects(docs, "docs", "docs/redirects.yaml", parser)
+	if err != nil {
+		log.Fatal(err)
+	}
+	parser.Redirects = redirects
+
+	if *validate {
+		os.Exit(validateDocs(parser, actions, devices, redirects))
 	}
 
-	md := markdown.New()
-	parser := content.NewCachedMarkdownParser(docs, md, 5*time.Minute)
 	languages := content.NewFSLocalizer(docs, "docs")
-	searcher := search.NewService(docs)
-	handler := httpapi.NewHandler(parser, languages, searcher)
+	searcher := search.NewService(docs, cfg.Search)
+	searcher.Metadata = search.ParserMetadata{Parser: parser}
+	if cfg.Search.AnalyticsFile != "" {
+		file, err := analytics.NewJSONLSink(cfg.Search.AnalyticsFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		sink := analytics.NewAsyncSink(file, 1024)
+		defer sink.Close()
+		searcher.Analytics = sink
+	}
+
+	readiness := health.NewChecker()
+	readiness.Add("docs", true, health.DocsCheck(docs, "docs", parser))
+	if cfg.Search.Enabled {
+		readiness.Add("searchIndex", true, health.SearchIndexCheck(searcher, languages))
+		// with the lexical fallback the search keeps working without the embeddings server
+		readiness.Add("embeddings", !cfg.Search.LexicalFallback, health.EmbeddingsCheck(searcher))
+	}
+
+	handler := httpapi.NewHandler(parser, languages, searcher, images, actions, devices, cfg, readiness)
+
+	languageList, err := languages.List()
+	if err != nil {
+		log.Fatal(fmt.Errorf("failed to list languages: %w", err))
+	}
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("/languages", handler.LanguagesList)
-	mux.HandleFunc("/capabilities", handler.Capabilities)
-	mux.HandleFunc("/search/", handler.Search)
-	mux.HandleFunc("/", handler.Content)
+	mux.Handle("GET /metrics", metrics.Handler())
+	handler.RegisterRoutes(mux, languageList)
 
+	port := strconv.Itoa(cfg.Port)
 	server := &http.Server{
 		Addr:    ":" + port,
-		Handler: mux,
+		Handler: clientinfo.Middleware(httpx.LoggingMiddleware(logger, tracing.Middleware(metrics.Middleware(httpapi.JSONErrors(mux))))),
 	}
 
 	go func() {
-		log.Println("Server is starting on port " + port)
+		slog.Info("server is starting", "port", cfg.Port)
 		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Fatal(fmt.Errorf("failed to start server: %w", err))
 		}
 	}()
 
 	<-gracefulShutdown
-	log.Println("Shutdown requested, shutting down...")
+	slog.Info("shutdown requested, shutting down")
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	_ = server.Shutdown(shutdownCtx)
+	if exporter != nil {
+		_ = exporter.Shutdown(shutdownCtx)
+	}
+}
+
+func newTraceExporter(cfg config.TracingConfig) tracing.Exporter {
+	switch cfg.Exporter {
+	case config.TracingStdout:
+		return tracing.NewStdoutExporter(os.Stdout)
+	case config.TracingOTLP:
+		return tracing.NewOTLPExporter(cfg.Endpoint, cfg.ServiceName)
+	default:
+		return nil
+	}
+}
+
+func printSearchReport(file string, lowScores map[string]float32) int {
+	if file == "" {
+		log.Println("no analytics file configured, set SEARCH_ANALYTICS_FILE")
+		return 1
+	}
+
+	raw, err := os.Open(file)
+	if err != nil {
+		log.Println(fmt.Errorf("failed to open analytics file: %w", err))
+		return 1
+	}
+	defer raw.Close()
+
+	events, err := analytics.ReadJSONL(raw)
+	if err != nil {
+		log.Println(err)
+		return 1
+	}
+
+	report := analytics.NewReport(events, analytics.ReportOptions{Limit: 20, LowScores: lowScores})
+	if err := report.Print(os.Stdout); err != nil {
+		log.Println(err)
+		return 1
+	}
+	return 0
+}
+
+func validateDocs(parser content.Parser, actions *content.ActionRegistry, devices *content.DeviceRegistry, redirects *content.Redirects) int {
+	problems, err := content.NewValidator(docs, "docs", parser, actions, devices, redirects).Validate()
+	if err != nil {
+		log.Println(err)
+		return 1
+	}
+
+	if err := httpapi.CheckOpenAPI(); err != nil {
+		problems = append(problems, content.Problem{Path: "internal/httpapi/openapi.json", Message: err.Error()})
+	}
+
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+	if len(problems) > 0 {
+		fmt.Printf("Found %d problem(s)\n", len(problems))
+		return 1
+	}
+
+	fmt.Println("No problems found")
+	return 0
 }